@@ -0,0 +1,36 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/Roverr/rtsp-stream/core/auth"
+)
+
+// canAccess shows if the claims allow the stream with the given ID, alias and URI.
+// Every stream is allowed while authentication is disabled.
+func (c *Controller) canAccess(claims *auth.Claim, id string, alias string, uri string) bool {
+	if !c.spec.JWTEnabled {
+		return true
+	}
+	return claims != nil && claims.CanAccess(id, alias, uri)
+}
+
+// canAccessRef shows if the token of the request to the endpoint allows the stream referenced by its ID or alias,
+// including preloads that are not started yet
+func (c *Controller) canAccessRef(r *http.Request, endpoint string, ref string) bool {
+	if !c.spec.JWTEnabled {
+		return true
+	}
+	id, alias, uri := ref, "", ""
+	if resolved, ok := c.registry.ResolveAlias(ref); ok {
+		id, alias = resolved, ref
+	} else if setting, ok := c.registry.Preload(ref); ok {
+		id, alias, uri = "", ref, setting.Uri
+	} else {
+		alias = c.registry.AliasOf(ref)
+	}
+	if stream, ok := c.registry.Get(id); ok {
+		uri = stream.OriginalURI
+	}
+	return c.canAccess(c.claims(r, endpoint), id, alias, uri)
+}
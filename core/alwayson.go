@@ -0,0 +1,55 @@
+package core
+
+import (
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/sirupsen/logrus"
+)
+
+// setAlwaysOn pins the stream so it keeps running without viewers, or unpins it
+func (c *Controller) setAlwaysOn(id string, on bool) {
+	opts := c.registry.Options(id)
+	if opts.AlwaysOn == on {
+		return
+	}
+	opts.AlwaysOn = on
+	c.registry.SetOptions(id, opts)
+	c.persist()
+}
+
+// startAlwaysOn starts the always on listen entries not started yet and restarts the always on streams
+// which are not running, unless the supervisor is restarting them already. Starts happen in the background
+// within the limits, the ones over them are tried again on the next run.
+func (c *Controller) startAlwaysOn() {
+	for alias, setting := range c.registry.Preloads() {
+		if !setting.AlwaysOn {
+			continue
+		}
+		if _, started := c.registry.ResolveAlias(alias); started {
+			continue
+		}
+		logrus.Infof("%s is always on, starting | AlwaysOn", alias)
+		go func(setting config.ListenSetting) {
+			if err := c.startAdmitted(setting.Uri, func() { c.startPreloadStream(setting) }); err != nil {
+				logrus.Infof("%s could not be started: %s | AlwaysOn", setting.Alias, err)
+			}
+		}(setting)
+	}
+	for id, stream := range c.registry.Streams() {
+		opts := c.registry.Options(id)
+		if !opts.AlwaysOn || c.supervisor.Watching(id) || c.blacklist.IsBanned(stream.OriginalURI) {
+			continue
+		}
+		stream.Mux.Lock()
+		running := stream.Running
+		stream.Mux.Unlock()
+		if running {
+			continue
+		}
+		logrus.Infof("%s is always on, restarting | AlwaysOn", id)
+		go func(id string, uri string, opts config.StreamOptions) {
+			if err := c.startAdmitted(uri, func() { c.startStream(uri, "", opts) }); err != nil {
+				logrus.Infof("%s could not be restarted: %s | AlwaysOn", id, err)
+			}
+		}(id, stream.OriginalURI, opts)
+	}
+}
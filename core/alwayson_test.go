@@ -0,0 +1,57 @@
+package core
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Roverr/hotstreak"
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/transcoder"
+	"github.com/riltech/streamer"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIdleStream(id string, uri string) *streamer.Stream {
+	return &streamer.Stream{
+		ID:          id,
+		OriginalURI: uri,
+		Running:     true,
+		Mux:         &sync.Mutex{},
+		Process:     transcoder.NewProcess(config.Profile{}, transcoder.Recording{}),
+		Streak:      hotstreak.New(hotstreak.Config{Limit: 10, HotWait: time.Minute, ActiveWait: time.Minute}),
+	}
+}
+
+func TestAlwaysOnCleanup(t *testing.T) {
+	c := newTestController()
+	pinned := newIdleStream("pinned", "rtsp://host/1")
+	idle := newIdleStream("idle", "rtsp://host/2")
+	c.registry.Add(pinned, "").Add(idle, "")
+	c.setAlwaysOn("pinned", true)
+
+	c.stopInactiveStreams()
+	assert.True(t, pinned.Running)
+	assert.False(t, idle.Running)
+}
+
+func TestStartAlwaysOn(t *testing.T) {
+	c := newTestController()
+	pinned := newIdleStream("pinned", "rtsp://host/1")
+	idle := newIdleStream("idle", "rtsp://host/2")
+	pinned.Running = false
+	idle.Running = false
+	c.registry.Add(pinned, "").Add(idle, "")
+	c.registry.SetOptions("pinned", config.StreamOptions{AlwaysOn: true})
+
+	c.startAlwaysOn()
+	assert.Eventually(t, func() bool {
+		pinned.Mux.Lock()
+		defer pinned.Mux.Unlock()
+		return pinned.Running
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, idle.Running)
+
+	c.setAlwaysOn("pinned", false)
+	assert.False(t, c.registry.Options("pinned").AlwaysOn)
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Claim describes the claim for the token
+type Claim struct {
+	Secret    string     `json:"secret"` // Legacy shared secret, checked against the secret of the endpoint
+	Subject   string     `json:"sub"`
+	Issuer    string     `json:"iss"`
+	Audience  StringList `json:"aud"`
+	ExpiresAt int64      `json:"exp"`
+	NotBefore int64      `json:"nbf"`
+	Scopes    StringList `json:"scopes"`  // Scopes granted by the token, like stream:view
+	Streams   StringList `json:"streams"` // IDs, aliases or URI patterns of the streams allowed for the token, empty allows every stream
+}
+
+// StringList is a list of strings that can also be given as a single space separated string
+type StringList []string
+
+// UnmarshalJSON decodes either a list or a space separated string
+func (l *StringList) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err == nil {
+		*l = strings.Fields(value)
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(b, &values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+// Contains shows if the value is in the list
+func (l StringList) Contains(value string) bool {
+	for _, item := range l {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccess shows if the token allows the stream with the given ID, alias and URI.
+// Entries with a scheme are matched against the URI, where * matches any sequence of characters.
+func (c Claim) CanAccess(id string, alias string, uri string) bool {
+	if len(c.Streams) == 0 {
+		return true
+	}
+	for _, entry := range c.Streams {
+		if strings.Contains(entry, "://") {
+			if uri != "" && matchPattern(entry, uri) {
+				return true
+			}
+			continue
+		}
+		if entry == id && id != "" || entry == alias && alias != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches the value against the pattern, where * matches any sequence of characters
+func matchPattern(pattern string, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 {
+			return strings.HasSuffix(value, part)
+		}
+		index := strings.Index(value, part)
+		if index < 0 {
+			return false
+		}
+		value = value[index+len(part):]
+	}
+	return value == ""
+}
+
+// Valid shows if the claim is valid at the current time
+func (c Claim) Valid() error {
+	now := time.Now().Unix()
+	if c.ExpiresAt != 0 && now >= c.ExpiresAt {
+		return errors.New("Token is expired")
+	}
+	if c.NotBefore != 0 && now < c.NotBefore {
+		return errors.New("Token is not valid yet")
+	}
+	return nil
+}
+
+// verify checks the issuer and the audience of the claim, empty values accept anything
+func (c Claim) verify(issuer string, audience string) error {
+	if issuer != "" && c.Issuer != issuer {
+		return fmt.Errorf("Unexpected issuer: %s", c.Issuer)
+	}
+	if audience != "" && !c.Audience.Contains(audience) {
+		return fmt.Errorf("Unexpected audience: %s", strings.Join(c.Audience, " "))
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
+)
+
+// jwk describes a single key of a JSON Web Key Set
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwkSet describes a JSON Web Key Set, or an OpenID configuration pointing to one
+type jwkSet struct {
+	Keys    []jwk  `json:"keys"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// verifyKey is a public key ready to verify tokens
+type verifyKey struct {
+	alg   string // Algorithm the key is restricted to, empty allows every algorithm of its type
+	value interface{}
+}
+
+// JWKSProvider validates tokens with the keys published in a JSON Web Key Set
+type JWKSProvider struct {
+	mu         *sync.RWMutex
+	source     string
+	client     *http.Client
+	keys       map[string]verifyKey
+	refreshed  time.Time
+	minRefresh time.Duration // Unknown key IDs trigger a refresh at most once in this period
+	issuer     string
+	audience   string
+}
+
+// Implementation check
+var _ JWT = (*JWKSProvider)(nil)
+
+// NewJWKSProvider loads the key set from the configured file or URL and refreshes it in the background
+func NewJWKSProvider(settings config.Auth) (*JWKSProvider, error) {
+	jp := &JWKSProvider{
+		mu:         &sync.RWMutex{},
+		source:     settings.JWKSURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		keys:       map[string]verifyKey{},
+		minRefresh: time.Minute,
+		issuer:     settings.JWTIssuer,
+		audience:   settings.JWTAudience,
+	}
+	if err := jp.Refresh(); err != nil {
+		return nil, err
+	}
+	if settings.JWKSRefresh > 0 {
+		go func() {
+			for {
+				<-time.After(settings.JWKSRefresh)
+				if err := jp.Refresh(); err != nil {
+					logrus.Errorf("Could not refresh keys: %s | JWKSProvider", err)
+				}
+			}
+		}()
+	}
+	return jp, nil
+}
+
+// Refresh loads the keys again, the previous keys are kept if it fails
+func (jp *JWKSProvider) Refresh() error {
+	set, err := jp.fetch(jp.source)
+	if err != nil {
+		return err
+	}
+	// OpenID configurations point to the key set
+	if len(set.Keys) == 0 && set.JWKSURI != "" {
+		if set, err = jp.fetch(set.JWKSURI); err != nil {
+			return err
+		}
+	}
+	keys := map[string]verifyKey{}
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		value, err := key.publicKey()
+		if err != nil {
+			logrus.Warnf("Skipping key %s: %s | JWKSProvider", key.Kid, err)
+			continue
+		}
+		keys[key.Kid] = verifyKey{alg: key.Alg, value: value}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("No usable keys found in %s", jp.source)
+	}
+	jp.mu.Lock()
+	defer jp.mu.Unlock()
+	jp.keys = keys
+	jp.refreshed = time.Now()
+	logrus.Debugf("%d keys are loaded from %s | JWKSProvider", len(keys), jp.source)
+	return nil
+}
+
+// fetch reads the document from a HTTP URL or a local file
+func (jp *JWKSProvider) fetch(source string) (*jwkSet, error) {
+	var b []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := jp.client.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Unexpected status code from %s: %d", source, resp.StatusCode)
+		}
+		if b, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if b, err = ioutil.ReadFile(source); err != nil {
+			return nil, err
+		}
+	}
+	set := &jwkSet{}
+	if err := json.Unmarshal(b, set); err != nil {
+		return nil, fmt.Errorf("Invalid key set in %s: %s", source, err)
+	}
+	return set, nil
+}
+
+// Validate is for validating if the given token is authenticated
+func (jp *JWKSProvider) Validate(tokenString string) (*jwt.Token, *Claim) {
+	ts := strings.Replace(tokenString, "Bearer ", "", -1)
+	if ts == "" {
+		logrus.Debug("No token found")
+		return nil, nil
+	}
+	claims := &Claim{}
+	token, err := jwt.ParseWithClaims(ts, claims, jp.verify)
+	if err != nil {
+		logrus.Errorf("Error at token verification: %s | JWKSProvider", err)
+		return nil, nil
+	}
+	if err := claims.verify(jp.issuer, jp.audience); err != nil {
+		logrus.Errorf("Error at token verification: %s | JWKSProvider", err)
+		return nil, nil
+	}
+	return token, claims
+}
+
+// verify selects the key of the token by its ID, refreshing the keys if it is unknown
+func (jp *JWKSProvider) verify(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := jp.lookup(kid)
+	if !ok && jp.mayRefresh() {
+		if err := jp.Refresh(); err != nil {
+			logrus.Errorf("Could not refresh keys: %s | JWKSProvider", err)
+		}
+		key, ok = jp.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("Unknown key: %s", kid)
+	}
+	if key.alg != "" && key.alg != token.Method.Alg() {
+		return nil, fmt.Errorf("Unexpected signing method for key %s: %v", kid, token.Header["alg"])
+	}
+	if !compatible(token.Method, key.value) {
+		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.value, nil
+}
+
+// lookup returns the key with the given ID. Tokens without ID can use the only key of the set.
+func (jp *JWKSProvider) lookup(kid string) (verifyKey, bool) {
+	jp.mu.RLock()
+	defer jp.mu.RUnlock()
+	if kid == "" && len(jp.keys) == 1 {
+		for _, key := range jp.keys {
+			return key, true
+		}
+	}
+	key, ok := jp.keys[kid]
+	return key, ok
+}
+
+// mayRefresh shows if the keys can be refreshed for an unknown key ID, so invalid tokens cannot flood the source
+func (jp *JWKSProvider) mayRefresh() bool {
+	jp.mu.Lock()
+	defer jp.mu.Unlock()
+	if time.Since(jp.refreshed) < jp.minRefresh {
+		return false
+	}
+	jp.refreshed = time.Now()
+	return true
+}
+
+// compatible shows if the signing method can be used with the key
+func compatible(method jwt.SigningMethod, key interface{}) bool {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := key.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		_, ok := key.(*ecdsa.PublicKey)
+		return ok
+	case *signingMethodEd25519:
+		_, ok := key.(ed25519.PublicKey)
+		return ok
+	}
+	return false
+}
+
+// publicKey decodes the public key of the JWK
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("Invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("Unsupported curve: %s", k.Crv)
+		}
+		x, err := decodeBase64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("Invalid EC key")
+		}
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("Unsupported curve: %s", k.Crv)
+		}
+		x, err := decodeBase64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("Invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("Unsupported key type: %s", k.Kty)
+}
+
+// decodeBase64 decodes base64url encoded values with or without padding
+func decodeBase64(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
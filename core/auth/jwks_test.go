@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jwk {
+	return jwk{Kid: kid, Kty: "RSA", Alg: "RS256", Use: "sig", N: encode(key.N.Bytes()), E: encode(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jwk {
+	return jwk{Kid: kid, Kty: "EC", Crv: "P-256", X: encode(key.X.Bytes()), Y: encode(key.Y.Bytes())}
+}
+
+func edJWK(kid string, key ed25519.PublicKey) jwk {
+	return jwk{Kid: kid, Kty: "OKP", Crv: "Ed25519", X: encode(key)}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "alice"})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	tokenString, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return tokenString
+}
+
+func TestJWKSProvider(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	mu := &sync.Mutex{}
+	set := jwkSet{Keys: []jwk{
+		rsaJWK("rsa", &rsaKey.PublicKey),
+		ecJWK("ec", &ecKey.PublicKey),
+		edJWK("ed", edPublic),
+		{Kid: "enc", Kty: "RSA", Use: "enc", N: encode(rsaKey.N.Bytes()), E: "AQAB"},
+		{Kid: "unknown", Kty: "oct"},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/.well-known/openid-configuration" {
+			json.NewEncoder(w).Encode(jwkSet{JWKSURI: "http://" + r.Host + "/keys"})
+			return
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer server.Close()
+
+	provider, err := NewJWKSProvider(config.Auth{JWKSURL: server.URL + "/.well-known/openid-configuration"})
+	assert.Nil(t, err)
+	assert.Len(t, provider.keys, 3)
+
+	for kid, tokenString := range map[string]string{
+		"rsa": sign(t, jwt.SigningMethodRS256, "rsa", rsaKey),
+		"ec":  sign(t, jwt.SigningMethodES256, "ec", ecKey),
+		"ed":  sign(t, SigningMethodEdDSA, "ed", edPrivate),
+	} {
+		token, claims := provider.Validate("Bearer " + tokenString)
+		assert.NotNil(t, token, kid)
+		assert.Equal(t, "alice", claims.Subject, kid)
+	}
+
+	// keys are bound to their algorithm and type
+	token, _ := provider.Validate(sign(t, jwt.SigningMethodRS384, "rsa", rsaKey))
+	assert.Nil(t, token)
+	token, _ = provider.Validate(sign(t, jwt.SigningMethodHS256, "rsa", []byte("secret")))
+	assert.Nil(t, token)
+	token, _ = provider.Validate(sign(t, jwt.SigningMethodES256, "rsa", ecKey))
+	assert.Nil(t, token)
+	token, _ = provider.Validate(sign(t, jwt.SigningMethodRS256, "", rsaKey))
+	assert.Nil(t, token)
+
+	// unknown keys trigger a refresh, but not more often than allowed
+	mu.Lock()
+	set.Keys = append(set.Keys, rsaJWK("rotated", &rotated.PublicKey))
+	mu.Unlock()
+	token, _ = provider.Validate(sign(t, jwt.SigningMethodRS256, "rotated", rotated))
+	assert.Nil(t, token)
+	provider.minRefresh = 0
+	token, _ = provider.Validate(sign(t, jwt.SigningMethodRS256, "rotated", rotated))
+	assert.NotNil(t, token)
+}
+
+func TestJWKSFile(t *testing.T) {
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	dir, err := ioutil.TempDir("", "jwks")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	b, err := json.Marshal(jwkSet{Keys: []jwk{edJWK("", edPrivate.Public().(ed25519.PublicKey))}})
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(path, b, 0644))
+
+	provider, err := NewJWKSProvider(config.Auth{JWKSURL: path})
+	assert.Nil(t, err)
+	// a single key is used for tokens without key ID
+	token, _ := provider.Validate(sign(t, SigningMethodEdDSA, "", edPrivate))
+	assert.NotNil(t, token)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"keys":[]}`), 0644))
+	assert.NotNil(t, provider.Refresh())
+	token, _ = provider.Validate(sign(t, SigningMethodEdDSA, "", edPrivate))
+	assert.NotNil(t, token)
+
+	_, err = NewJWKSProvider(config.Auth{JWKSURL: filepath.Join(dir, "missing.json")})
+	assert.NotNil(t, err)
+}
@@ -16,25 +16,33 @@ type JWT interface {
 	Validate(token string) (*jwt.Token, *Claim)
 }
 
-// Claim describes the claim for the token
-type Claim struct {
-	Secret string `json:"secret"`
-}
-
-// Valid shows if the claim is valid or not
-func (c Claim) Valid() error {
-	return nil
-}
-
 // JWTProvider implements the validate method
 type JWTProvider struct {
 	secret    []byte
 	verifyKey *rsa.PublicKey
+	issuer    string
+	audience  string
 }
 
 // Implementation check
 var _ JWT = (*JWTProvider)(nil)
 
+// NewProvider returns the provider of the configured decoding method
+func NewProvider(settings config.Auth) (JWT, error) {
+	if strings.ToLower(settings.JWTMethod) == "jwks" {
+		provider, err := NewJWKSProvider(settings)
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
+	}
+	provider, err := NewJWTProvider(settings)
+	if err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
 // NewJWTProvider returns a new pointer for the created provider
 func NewJWTProvider(settings config.Auth) (*JWTProvider, error) {
 	switch strings.ToLower(settings.JWTMethod) {
@@ -47,9 +55,9 @@ func NewJWTProvider(settings config.Auth) (*JWTProvider, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &JWTProvider{verifyKey: verifyKey}, nil
+		return &JWTProvider{verifyKey: verifyKey, issuer: settings.JWTIssuer, audience: settings.JWTAudience}, nil
 	default:
-		return &JWTProvider{secret: []byte(settings.JWTSecret)}, nil
+		return &JWTProvider{secret: []byte(settings.JWTSecret), issuer: settings.JWTIssuer, audience: settings.JWTAudience}, nil
 	}
 }
 
@@ -66,16 +74,25 @@ func (jp JWTProvider) Validate(tokenString string) (*jwt.Token, *Claim) {
 		logrus.Errorf("Error at token verification: %s | JWTProvider", err)
 		return nil, nil
 	}
+	if err := claims.verify(jp.issuer, jp.audience); err != nil {
+		logrus.Errorf("Error at token verification: %s | JWTProvider", err)
+		return nil, nil
+	}
 	return token, claims
 }
 
-// verify is to check the signing method and return the secret
+// verify is to check the signing method and return the secret.
+// Only the methods of the configured key are accepted, so a public key cannot be used as an HMAC secret
+// and tokens signed with the empty secret of the rsa mode are refused.
 func (jp JWTProvider) verify(token *jwt.Token) (interface{}, error) {
+	if jp.verifyKey != nil {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
+			return jp.verifyKey, nil
+		}
+		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	}
 	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
 		return jp.secret, nil
 	}
-	if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
-		return jp.verifyKey, nil
-	}
 	return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
 }
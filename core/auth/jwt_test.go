@@ -5,6 +5,7 @@ import (
 	"crypto/rsa"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Roverr/rtsp-stream/core/config"
 	jwt "github.com/dgrijalva/jwt-go"
@@ -12,7 +13,7 @@ import (
 )
 
 func TestJWTAuthWithSecret(t *testing.T) {
-	spec := config.InitConfig()
+	spec := config.InitConfig(nil)
 	provider, err := NewJWTProvider(spec.Auth)
 	assert.Nil(t, err)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{})
@@ -36,4 +37,44 @@ func TestJWTAuthWithRSA(t *testing.T) {
 	assert.Nil(t, err)
 	validated, _ := provider.Validate(tokenString)
 	assert.NotNil(t, validated)
+	signed := tokenString
+
+	// HMAC tokens are not accepted in rsa mode, whatever key they are signed with
+	for _, secret := range [][]byte{{}, []byte("secret")} {
+		tokenString, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{}).SignedString(secret)
+		assert.Nil(t, err)
+		validated, _ = provider.Validate(tokenString)
+		assert.Nil(t, validated)
+	}
+
+	// and RSA tokens are not accepted in secret mode
+	provider = JWTProvider{secret: []byte("secret")}
+	validated, _ = provider.Validate(signed)
+	assert.Nil(t, validated)
+}
+
+func TestJWTStandardClaims(t *testing.T) {
+	provider := JWTProvider{secret: []byte("secret"), issuer: "https://id.example.com", audience: "player"}
+	sign := func(claims jwt.MapClaims) string {
+		tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		assert.Nil(t, err)
+		return tokenString
+	}
+	valid := jwt.MapClaims{"iss": "https://id.example.com", "aud": []string{"player"}, "sub": "alice", "exp": time.Now().Add(time.Minute).Unix()}
+	validated, claims := provider.Validate(sign(valid))
+	assert.NotNil(t, validated)
+	assert.Equal(t, "alice", claims.Subject)
+
+	expired := jwt.MapClaims{"iss": "https://id.example.com", "aud": "player", "exp": time.Now().Add(-time.Minute).Unix()}
+	validated, _ = provider.Validate(sign(expired))
+	assert.Nil(t, validated)
+	notYet := jwt.MapClaims{"iss": "https://id.example.com", "aud": "player", "nbf": time.Now().Add(time.Minute).Unix()}
+	validated, _ = provider.Validate(sign(notYet))
+	assert.Nil(t, validated)
+	otherIssuer := jwt.MapClaims{"iss": "https://other.example.com", "aud": "player"}
+	validated, _ = provider.Validate(sign(otherIssuer))
+	assert.Nil(t, validated)
+	otherAudience := jwt.MapClaims{"iss": "https://id.example.com", "aud": "api"}
+	validated, _ = provider.Validate(sign(otherAudience))
+	assert.Nil(t, validated)
 }
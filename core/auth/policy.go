@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"sync"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+)
+
+// Scopes used by the endpoints of the application
+const (
+	ScopeStart      = "stream:start"
+	ScopeStop       = "stream:stop"
+	ScopeList       = "stream:list"
+	ScopeView       = "stream:view"
+	ScopeListen     = "stream:listen"
+	ScopeRecordings = "recordings:view"
+	ScopeEvents     = "events:read"
+	ScopeMetrics    = "metrics:read"
+)
+
+// IPolicy describes the user panel of the policy table
+type IPolicy interface {
+	Register(endpoint string, scopes ...string) *Policy
+	Scopes(endpoint string, setting config.EndpointSetting) []string
+	Allows(endpoint string, setting config.EndpointSetting, claims *Claim) bool
+}
+
+// Policy maps the endpoints to the scopes a token needs to reach them
+type Policy struct {
+	mu     *sync.RWMutex
+	scopes map[string][]string
+}
+
+// Type check
+var _ IPolicy = (*Policy)(nil)
+
+// NewPolicy creates a new empty Policy
+func NewPolicy() *Policy {
+	return &Policy{
+		mu:     &sync.RWMutex{},
+		scopes: map[string][]string{},
+	}
+}
+
+// Register sets the default scopes of the endpoint, any of them grants access to it
+func (p *Policy) Register(endpoint string, scopes ...string) *Policy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scopes[endpoint] = scopes
+	return p
+}
+
+// Scopes returns the scopes accepted for the endpoint, the ones in the setting win over the registered ones
+func (p *Policy) Scopes(endpoint string, setting config.EndpointSetting) []string {
+	if len(setting.Scopes) > 0 {
+		return setting.Scopes
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.scopes[endpoint]
+}
+
+// Allows shows if the claims grant access to the endpoint.
+// Tokens with scopes need one of the scopes of the endpoint. Tokens without scopes are checked
+// against the secret of the endpoint instead, unless the setting requires scopes explicitly.
+func (p *Policy) Allows(endpoint string, setting config.EndpointSetting, claims *Claim) bool {
+	if claims == nil {
+		return false
+	}
+	if len(claims.Scopes) == 0 {
+		if len(setting.Scopes) > 0 {
+			return false
+		}
+		return setting.Secret == "" || claims.Secret == setting.Secret
+	}
+	for _, scope := range p.Scopes(endpoint, setting) {
+		if claims.Scopes.Contains(scope) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	p := NewPolicy().Register("static", ScopeView).Register("stop", ScopeStop)
+	open := config.EndpointSetting{Enabled: true}
+	secret := config.EndpointSetting{Enabled: true, Secret: "macilaci"}
+	scoped := config.EndpointSetting{Enabled: true, Scopes: []string{"admin"}}
+
+	assert.Equal(t, []string{ScopeView}, p.Scopes("static", open))
+	assert.Equal(t, []string{"admin"}, p.Scopes("static", scoped))
+	assert.Empty(t, p.Scopes("unknown", open))
+
+	assert.False(t, p.Allows("static", open, nil))
+	// tokens without scopes use the secret
+	assert.True(t, p.Allows("static", open, &Claim{}))
+	assert.False(t, p.Allows("stop", secret, &Claim{}))
+	assert.True(t, p.Allows("stop", secret, &Claim{Secret: "macilaci"}))
+	assert.False(t, p.Allows("stop", scoped, &Claim{Secret: "macilaci"}))
+	// tokens with scopes need one of the endpoint
+	viewer := &Claim{Scopes: StringList{ScopeView}}
+	assert.True(t, p.Allows("static", open, viewer))
+	assert.True(t, p.Allows("static", secret, viewer))
+	assert.False(t, p.Allows("stop", open, viewer))
+	assert.False(t, p.Allows("static", scoped, viewer))
+	assert.True(t, p.Allows("static", scoped, &Claim{Scopes: StringList{"admin"}}))
+	assert.False(t, p.Allows("unknown", open, viewer))
+}
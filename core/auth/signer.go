@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Query parameters of signed URLs
+const (
+	ExpiresParam   = "expires"
+	SignatureParam = "signature"
+)
+
+// ErrInvalidSignature describes an error when the signature of an URL does not match
+var ErrInvalidSignature = errors.New("Invalid signature")
+
+// ErrSignatureExpired describes an error when the signature of an URL is not valid anymore
+var ErrSignatureExpired = errors.New("Signature is expired")
+
+// ErrSigningDisabled describes an error when URLs are not signed by the application
+var ErrSigningDisabled = errors.New("Signing is disabled")
+
+// ISigner describes the user panel of the URL signer
+type ISigner interface {
+	Sign(ref string, expires time.Time) url.Values
+	Verify(ref string, query url.Values) error
+	Expiry() time.Duration
+}
+
+// Signer signs playback URLs of streams with HMAC-SHA256, so they can be used without tokens until they expire
+type Signer struct {
+	secret []byte
+	expiry time.Duration
+}
+
+// Type check
+var _ ISigner = (*Signer)(nil)
+
+// NewSigner creates a new Signer. Expiry is the longest time a signature can be valid for.
+func NewSigner(secret string, expiry time.Duration) *Signer {
+	return &Signer{secret: []byte(secret), expiry: expiry}
+}
+
+// Sign returns the query parameters granting access to the files of the stream referenced by its ID or alias
+func (s *Signer) Sign(ref string, expires time.Time) url.Values {
+	if s == nil {
+		return url.Values{}
+	}
+	return url.Values{
+		ExpiresParam:   {strconv.FormatInt(expires.Unix(), 10)},
+		SignatureParam: {hex.EncodeToString(s.mac(ref, expires.Unix()))},
+	}
+}
+
+// Verify checks if the query parameters hold a valid signature for the stream
+func (s *Signer) Verify(ref string, query url.Values) error {
+	if s == nil {
+		return ErrSigningDisabled
+	}
+	expires, err := strconv.ParseInt(query.Get(ExpiresParam), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	signature, err := hex.DecodeString(query.Get(SignatureParam))
+	if err != nil || !hmac.Equal(signature, s.mac(ref, expires)) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return ErrSignatureExpired
+	}
+	return nil
+}
+
+// Expiry returns the longest time a signature can be valid for, 0 if signing is disabled
+func (s *Signer) Expiry() time.Duration {
+	if s == nil {
+		return 0
+	}
+	return s.expiry
+}
+
+// mac computes the signature of the stream reference and the expiry
+func (s *Signer) mac(ref string, expires int64) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(h, "%s\n%d", ref, expires)
+	return h.Sum(nil)
+}
+
+// Signed shows if the query parameters carry a signature
+func Signed(query url.Values) bool {
+	return query.Get(SignatureParam) != ""
+}
+
+// Expires returns the expiry of the signature carried by the query parameters
+func Expires(query url.Values) time.Time {
+	expires, _ := strconv.ParseInt(query.Get(ExpiresParam), 10, 64)
+	return time.Unix(expires, 0)
+}
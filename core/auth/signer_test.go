@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner(t *testing.T) {
+	s := NewSigner("secret", time.Hour)
+	assert.Equal(t, time.Hour, s.Expiry())
+	expires := time.Now().Add(time.Minute)
+	query := s.Sign("camera", expires)
+	assert.True(t, Signed(query))
+	assert.Equal(t, expires.Unix(), Expires(query).Unix())
+	assert.Nil(t, s.Verify("camera", query))
+	assert.Equal(t, ErrInvalidSignature, s.Verify("other", query))
+	assert.Equal(t, ErrInvalidSignature, NewSigner("other", time.Hour).Verify("camera", query))
+
+	tampered := url.Values{ExpiresParam: {"9999999999"}, SignatureParam: query[SignatureParam]}
+	assert.Equal(t, ErrInvalidSignature, s.Verify("camera", tampered))
+	assert.Equal(t, ErrInvalidSignature, s.Verify("camera", url.Values{SignatureParam: {"zz"}}))
+	assert.Equal(t, ErrSignatureExpired, s.Verify("camera", s.Sign("camera", time.Now().Add(-time.Minute))))
+	assert.False(t, Signed(url.Values{}))
+}
+
+func TestDisabledSigner(t *testing.T) {
+	s := (*Signer)(nil)
+	assert.Equal(t, time.Duration(0), s.Expiry())
+	assert.Empty(t, s.Sign("camera", time.Now()))
+	assert.Equal(t, ErrSigningDisabled, s.Verify("camera", url.Values{SignatureParam: {"00"}}))
+}
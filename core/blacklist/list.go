@@ -2,6 +2,7 @@ package blacklist
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -12,6 +13,8 @@ type IList interface {
 	AddOrIncrease(uri string) IList
 	IsBanned(uri string) bool
 	Remove(uri string) IList
+	BanCount() int
+	BannedCount() int
 }
 
 // List implements IList
@@ -19,6 +22,8 @@ type List struct {
 	list      *sync.Map
 	freeAfter time.Duration
 	limit     int
+	bans      int64
+	onBan     func(uri string)
 }
 
 // Type check
@@ -26,7 +31,17 @@ var _ IList = (*List)(nil)
 
 // NewList creates a new List instance
 func NewList(freeAfter time.Duration, limit int) *List {
-	return &List{&sync.Map{}, freeAfter, limit}
+	return &List{&sync.Map{}, freeAfter, limit, 0, nil}
+}
+
+// OnBan sets the function called with the URI whenever one gets banned.
+// It has to be set before the list is used.
+func (b *List) OnBan(handler func(uri string)) *List {
+	if b == nil {
+		return nil
+	}
+	b.onBan = handler
+	return b
 }
 
 // AddOrIncrease stores the URI as a new record or increases the inner counter
@@ -51,6 +66,10 @@ func (b *List) AddOrIncrease(uri string) IList {
 		logrus.Infof("%s is banned beacuse of reaching limit | Blacklist", uri)
 		ban := time.Now().Add(b.freeAfter)
 		record.(IRecord).SetBanTime(&ban).ResetCount()
+		atomic.AddInt64(&b.bans, 1)
+		if b.onBan != nil {
+			b.onBan(uri)
+		}
 	}
 	logrus.Debugf(
 		"%s is now with %d of %d on the blacklist | Blacklist",
@@ -81,3 +100,26 @@ func (b *List) Remove(uri string) IList {
 	b.list.Delete(uri)
 	return b
 }
+
+// BanCount returns how many times URIs got banned since the list was created
+func (b *List) BanCount() int {
+	if b == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&b.bans))
+}
+
+// BannedCount returns the number of URIs that are currently banned
+func (b *List) BannedCount() int {
+	if b == nil {
+		return 0
+	}
+	count := 0
+	b.list.Range(func(_, record interface{}) bool {
+		if record.(IRecord).IsBanned() {
+			count++
+		}
+		return true
+	})
+	return count
+}
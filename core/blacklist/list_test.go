@@ -17,7 +17,22 @@ func TestList(t *testing.T) {
 	list.AddOrIncrease(url)
 	list.AddOrIncrease(url)
 	assert.Equal(t, true, list.IsBanned(url))
+	assert.Equal(t, 1, list.BanCount())
+	assert.Equal(t, 1, list.BannedCount())
 	record, ok := list.list.Load(url)
 	assert.Equal(t, true, ok)
 	assert.Equal(t, true, record.(IRecord).GetBanTime().Before(time.Now().Add(time.Hour*1+1)))
 }
+
+func TestOnBan(t *testing.T) {
+	url := "rtsp://host.com/1"
+	banned := []string{}
+	list := NewList(time.Hour, 0).OnBan(func(uri string) {
+		banned = append(banned, uri)
+	})
+	list.AddOrIncrease(url)
+	assert.Empty(t, banned)
+	list.AddOrIncrease(url)
+	list.AddOrIncrease(url)
+	assert.Equal(t, []string{url}, banned)
+}
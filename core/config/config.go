@@ -1,8 +1,11 @@
 package config
 
 import (
+	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -21,10 +24,16 @@ type CORS struct {
 
 // Auth describes information regarding authentication
 type Auth struct {
-	JWTEnabled    bool   `envconfig:"AUTH_JWT_ENABLED" default:"false"`      // Indicates if JWT authentication is enabled or not
-	JWTSecret     string `envconfig:"AUTH_JWT_SECRET" default:"macilaci"`    // Secret of the JWT encryption
-	JWTMethod     string `envconfig:"AUTH_JWT_METHOD" default:"secret"`      // Can be "secret" or "rsa", defines the decoding method
-	JWTPubKeyPath string `envconfig:"AUTH_JWT_PUB_PATH" default:"./key.pub"` // Path to the public RSA key
+	JWTEnabled    bool          `envconfig:"AUTH_JWT_ENABLED" default:"false"`      // Indicates if JWT authentication is enabled or not
+	JWTSecret     string        `envconfig:"AUTH_JWT_SECRET" default:"macilaci"`    // Secret of the JWT encryption
+	JWTMethod     string        `envconfig:"AUTH_JWT_METHOD" default:"secret"`      // Can be "secret", "rsa" or "jwks", defines the decoding method
+	JWTPubKeyPath string        `envconfig:"AUTH_JWT_PUB_PATH" default:"./key.pub"` // Path to the public RSA key
+	JWTIssuer     string        `envconfig:"AUTH_JWT_ISSUER" default:""`            // Required iss claim of the tokens, empty accepts any issuer
+	JWTAudience   string        `envconfig:"AUTH_JWT_AUDIENCE" default:""`          // Required aud claim of the tokens, empty accepts any audience
+	JWKSURL       string        `envconfig:"AUTH_JWT_JWKS_URL" default:""`          // URL or path of the JSON Web Key Set or OpenID configuration used by the "jwks" method
+	JWKSRefresh   time.Duration `envconfig:"AUTH_JWT_JWKS_REFRESH" default:"1h"`    // Time period of reloading the key set
+	SignSecret    string        `envconfig:"AUTH_SIGN_SECRET" default:""`           // Secret of signed playback URLs, empty turns signing off
+	SignExpiry    time.Duration `envconfig:"AUTH_SIGN_EXPIRY" default:"1h"`         // Time signed playback URLs are valid for
 }
 
 // ProcessLogging describes information about the logging mechanism of the transcoding FFMPEG process
@@ -46,75 +55,239 @@ type Blacklist struct {
 
 // Process describes information regarding the transcoding process
 type Process struct {
-	CleanupEnabled bool          `envconfig:"CLEANUP_ENABLED" default:"true"` // Option to turn of cleanup
-	CleanupTime    time.Duration `envconfig:"CLEANUP_TIME" default:"2m0s"`    // Time period between process cleaning
-	StoreDir       string        `envconfig:"STORE_DIR" default:"./videos"`   // Directory to store / service video chunks
-	KeepFiles      bool          `envconfig:"KEEP_FILES" default:"false"`     // Option for not deleting files
-	Audio          bool          `envconfig:"AUDIO_ENABLED" default:"true"`   // Option for enabling audio
+	CleanupEnabled bool          `envconfig:"CLEANUP_ENABLED" default:"true"`  // Option to turn of cleanup
+	CleanupTime    time.Duration `envconfig:"CLEANUP_TIME" default:"2m0s"`     // Time period between process cleaning
+	StoreDir       string        `envconfig:"STORE_DIR" default:"./videos"`    // Directory to store / service video chunks
+	KeepFiles      bool          `envconfig:"KEEP_FILES" default:"false"`      // Option for not deleting files
+	Audio          bool          `envconfig:"AUDIO_ENABLED" default:"true"`    // Option for enabling audio
+	Dash           bool          `envconfig:"DASH_ENABLED" default:"false"`    // Option for emitting MPEG-DASH next to HLS
+	PersistEnabled bool          `envconfig:"PERSIST_ENABLED" default:"false"` // Option to keep known streams between restarts
+	PersistDir     string        `envconfig:"PERSIST_DIR" default:"./data"`    // Directory to store the state of known streams
+	SnapshotTime   time.Duration `envconfig:"SNAPSHOT_TIME" default:"10s"`     // Time period a snapshot is served for before taking a new one
+	IdleTimeout    time.Duration `envconfig:"IDLE_TIMEOUT" default:"2m"`       // Time without viewers before a stream is stopped by the cleanup
+	ViewerTimeout  time.Duration `envconfig:"VIEWER_TIMEOUT" default:"30s"`    // Time a client is counted as viewer after its last request
+}
+
+// Supervisor describes configuration for restarting failed transcoding processes
+type Supervisor struct {
+	SupervisorEnabled bool          `envconfig:"SUPERVISOR_ENABLED" default:"true"` // Option to restart streams when their process exits or stalls
+	StallTimeout      time.Duration `envconfig:"STALL_TIMEOUT" default:"30s"`       // Time without a new segment before a stream is restarted, 0 turns it off
+	RestartMinBackoff time.Duration `envconfig:"RESTART_MIN_BACKOFF" default:"1s"`  // Wait before the first restart attempt
+	RestartMaxBackoff time.Duration `envconfig:"RESTART_MAX_BACKOFF" default:"2m"`  // Upper limit of the wait between restart attempts
+}
+
+// WebhookDelivery describes configuration for sending webhooks
+type WebhookDelivery struct {
+	WebhookQueueSize int           `envconfig:"WEBHOOK_QUEUE_SIZE" default:"100"` // Number of deliveries waiting to be sent before new ones are dropped
+	WebhookRetries   int           `envconfig:"WEBHOOK_RETRIES" default:"5"`      // Number of retries of a failed delivery
+	WebhookBackoff   time.Duration `envconfig:"WEBHOOK_BACKOFF" default:"1s"`     // Wait before the first retry, doubled for every next one
+	WebhookTimeout   time.Duration `envconfig:"WEBHOOK_TIMEOUT" default:"5s"`     // Timeout of a single delivery
+}
+
+// Limits describes configuration for limiting the number of transcoding processes
+type Limits struct {
+	MaxStreams        int    `envconfig:"MAX_STREAMS" default:"0"`          // Maximum number of running streams, 0 means unlimited
+	MaxStreamsPerUser int    `envconfig:"MAX_STREAMS_PER_USER" default:"0"` // Maximum number of running streams started by the same JWT subject, 0 means unlimited
+	LimitPolicy       string `envconfig:"LIMIT_POLICY" default:"reject"`    // Handling of new streams over the limits, "reject" or "evict"
+}
+
+// Recording describes configuration for recording streams to disk
+type Recording struct {
+	RecordDir           string        `envconfig:"RECORD_DIR" default:"./recordings"`   // Directory to store recordings
+	RecordSegmentLength time.Duration `envconfig:"RECORD_SEGMENT_LENGTH" default:"10m"` // Length of each recorded file
+	RecordRetention     time.Duration `envconfig:"RECORD_RETENTION" default:"168h"`     // Maximum age of kept recordings, 0 keeps them forever
+	RecordQuota         int           `envconfig:"RECORD_QUOTA" default:"0"`            // Maximum size of kept recordings in megabytes, 0 means unlimited
+	RecordCleanupTime   time.Duration `envconfig:"RECORD_CLEANUP_TIME" default:"5m"`    // Time period between enforcing the retention of recordings
 }
 
 // Specification describes the application context settings
 type Specification struct {
-	Debug bool `envconfig:"DEBUG" default:"false"` // Indicates if debug log should be enabled or not
-	Port  int  `envconfig:"PORT" default:"8080"`   // Port that the application listens on
+	Debug           bool          `envconfig:"DEBUG" default:"false"`            // Indicates if debug log should be enabled or not
+	Port            int           `envconfig:"PORT" default:"8080"`              // Port that the application listens on
+	ConfigFile      string        `envconfig:"CONFIG" default:"rtsp-stream.yml"` // Path of the yml file describing endpoints, preloads and profiles
+	ConfigWatchTime time.Duration `envconfig:"CONFIG_WATCH_TIME" default:"5s"`   // Time period between checking the yml file for changes, 0 turns it off
 
 	CORS
 	Blacklist
+	Supervisor
+	WebhookDelivery
+	Limits
 	Auth
 	Process
 	ProcessLogging
+	Recording
 	EndpointYML
 }
 
 // EndpointSetting describes how a given endpoint works in the application
 type EndpointSetting struct {
-	Enabled bool   `yml:"enabled"`
-	Secret  string `yml:"secret"`
+	Enabled  bool     `yaml:"enabled"`
+	Secret   string   `yaml:"secret"`
+	Scopes   []string `yaml:"scopes"`   // Scopes accepted for the endpoint, overriding the default ones
+	Carriers []string `yaml:"carriers"` // Places the token is read from in order, "header" by default
 }
 
+// Token carriers of the endpoints
+const (
+	CarrierHeader = "header" // Authorization header
+	CarrierCookie = "cookie" // Cookie set by the login endpoint
+	CarrierQuery  = "query"  // access_token query parameter
+)
+
+// TokenCarriers returns the places the token of the endpoint is read from in order
+func (e EndpointSetting) TokenCarriers() []string {
+	if len(e.Carriers) == 0 {
+		return []string{CarrierHeader}
+	}
+	return e.Carriers
+}
+
+// EndpointNames lists the name of every endpoint
+var EndpointNames = []string{"list", "start", "static", "stop", "recordings", "snapshot", "listen", "sign", "login", "events", "metrics"}
+
+// ListenSetting describes a stream that is preloaded into the application
 type ListenSetting struct {
-	Enabled        bool          `yaml:"enabled"`
-	Uri    		   string 		 `yaml:"uri"`
-	Alias          string        `yaml:"alias"`
+	Enabled       bool   `yaml:"enabled" json:"enabled"`
+	Uri           string `yaml:"uri" json:"uri"`
+	Alias         string `yaml:"alias" json:"alias"`
+	StreamOptions `yaml:",inline"`
+}
+
+// Webhook describes an URL notified about stream events
+type Webhook struct {
+	URL    string   `yaml:"url"`
+	Secret string   `yaml:"secret"` // Key of the HMAC-SHA256 signature of the payload, empty means unsigned
+	Events []string `yaml:"events"` // Types of events sent, empty means every event
+}
+
+// Endpoints describes the settings of every endpoint
+type Endpoints struct {
+	Start      EndpointSetting `yaml:"start"`
+	Stop       EndpointSetting `yaml:"stop"`
+	List       EndpointSetting `yaml:"list"`
+	Static     EndpointSetting `yaml:"static"`
+	Metrics    EndpointSetting `yaml:"metrics"`
+	Recordings EndpointSetting `yaml:"recordings"`
+	Snapshot   EndpointSetting `yaml:"snapshot"`
+	Events     EndpointSetting `yaml:"events"`
+	Listen     EndpointSetting `yaml:"listen"`
+	Sign       EndpointSetting `yaml:"sign"`
+	Login      EndpointSetting `yaml:"login"`
+}
+
+// Setting returns the setting of the endpoint with the given name
+func (e Endpoints) Setting(name string) (EndpointSetting, bool) {
+	switch name {
+	case "start":
+		return e.Start, true
+	case "stop":
+		return e.Stop, true
+	case "list":
+		return e.List, true
+	case "static":
+		return e.Static, true
+	case "metrics":
+		return e.Metrics, true
+	case "recordings":
+		return e.Recordings, true
+	case "snapshot":
+		return e.Snapshot, true
+	case "events":
+		return e.Events, true
+	case "listen":
+		return e.Listen, true
+	case "sign":
+		return e.Sign, true
+	case "login":
+		return e.Login, true
+	}
+	return EndpointSetting{}, false
 }
 
 // EndpointYML describes the yml structure used
 type EndpointYML struct {
-	Version   string `yaml:"version"`
-	Endpoints struct {
-		Start  EndpointSetting `yaml:"start"`
-		Stop   EndpointSetting `yaml:"stop"`
-		List   EndpointSetting `yaml:"list"`
-		Static EndpointSetting `yaml:"static"`
-	} `yaml:"endpoints"`
-	Listen [] ListenSetting `yaml:"listen"`
-}
-
-// InitConfig is to initalise the config
-func InitConfig() *Specification {
+	Version   string                 `yaml:"version"`
+	Endpoints Endpoints              `yaml:"endpoints"`
+	Listen    []ListenSetting        `yaml:"listen"`
+	Profiles  map[string]Profile     `yaml:"profiles"`
+	Webhooks  []Webhook              `yaml:"webhooks"`
+	Settings  map[string]interface{} `yaml:"settings"` // Values of env variables, keyed by their lowercase name without prefix
+}
+
+// envPrefix is the prefix of every env variable read by the application
+const envPrefix = "RTSP_STREAM"
+
+// LoadEndpointYML reads and validates the yml file at the given path. Unknown keys are errors.
+func LoadEndpointYML(path string) (EndpointYML, error) {
+	setting := EndpointYML{}
+	dat, err := ioutil.ReadFile(path)
+	if err != nil {
+		return setting, err
+	}
+	if err = yaml.UnmarshalStrict(dat, &setting); err != nil {
+		return setting, err
+	}
+	return setting, setting.Validate()
+}
+
+// Load reads the settings and validates them. Every setting is taken from the first source defining it
+// in the order of command line flags, env variables, the settings section of the yml file and defaults.
+// A missing yml file is not an error, every endpoint is disabled in that case.
+func Load(args []string) (*Specification, error) {
 	var s Specification
-	err := envconfig.Process("RTSP_STREAM", &s)
+	if err := envconfig.Process(envPrefix, &s); err != nil {
+		return &s, err
+	}
+	flags, err := parseFlags(&s, args)
 	if err != nil {
-		log.Fatal(err.Error())
+		return &s, err
 	}
+	if path, ok := flags[configKey]; ok {
+		s.ConfigFile = path
+	}
+	problems := unknownEnv(os.Environ())
+	setting, err := LoadEndpointYML(s.ConfigFile)
+	s.EndpointYML = setting
+	if os.IsNotExist(err) {
+		logrus.Warnf("%s is not found, every endpoint is disabled | Config", s.ConfigFile)
+	} else if errs, ok := err.(Errors); ok {
+		for _, e := range errs {
+			problems = append(problems, fmt.Errorf("%s: %s", s.ConfigFile, e))
+		}
+	} else if err != nil {
+		problems = append(problems, fmt.Errorf("%s: %s", s.ConfigFile, err))
+	}
+	problems = problems.add(s.apply(setting.Settings, flags))
 	if s.Debug {
 		s.KeepFiles = true
 		s.Process.Audio = false
 		s.ProcessLogging.Enabled = true
 	}
-	setting := EndpointYML{}
-	defer func() {
-		s.EndpointYML = setting
-	}()
-	dat, err := ioutil.ReadFile("rtsp-stream.yml")
-	if err != nil {
-		logrus.Errorf("error: %v", err)
-		return &s
+	problems = problems.add(s.Validate())
+	return &s, problems.err()
+}
+
+// Check returns every problem of the configuration, including a missing yml file
+func Check(args []string) []error {
+	s, err := Load(args)
+	if err == flag.ErrHelp {
+		return nil
+	}
+	var problems Errors
+	if _, statErr := os.Stat(s.ConfigFile); statErr != nil {
+		problems = append(problems, statErr)
+	}
+	return problems.add(err)
+}
+
+// InitConfig is to initalise the config from the env variables, the yml file and the given command line arguments
+func InitConfig(args []string) *Specification {
+	s, err := Load(args)
+	if err == flag.ErrHelp {
+		os.Exit(0)
 	}
-	err = yaml.Unmarshal(dat, &setting)
 	if err != nil {
-		logrus.Errorf("error: %v", err)
-		return &s
+		log.Fatalf("Invalid configuration: %s", err)
 	}
-	return &s
+	return s
 }
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadEndpointYML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rtsp-stream.yml")
+
+	_, err = LoadEndpointYML(path)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`
+endpoints:
+  start:
+    enabled: true
+    secret: secret
+listen:
+  - uri: rtsp://host/1
+    alias: camera
+    enabled: true
+`), 0644))
+	yml, err := LoadEndpointYML(path)
+	assert.Nil(t, err)
+	assert.True(t, yml.Endpoints.Start.Enabled)
+	setting, ok := yml.Endpoints.Setting("start")
+	assert.True(t, ok)
+	assert.Equal(t, "secret", setting.Secret)
+	_, ok = yml.Endpoints.Setting("unknown")
+	assert.False(t, ok)
+	assert.Equal(t, "camera", yml.Listen[0].Alias)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`
+endpoints:
+  start:
+    enabled: true
+    secrte: secret
+`), 0644))
+	_, err = LoadEndpointYML(path)
+	assert.NotNil(t, err)
+}
+
+func TestBuildConfig(t *testing.T) {
+	_, err := LoadEndpointYML("../../build/rtsp-stream.yml")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Profile describes how the transcoding of a stream is done
+type Profile struct {
+	Codec          string        `yaml:"codec"`           // Video codec, "copy" keeps the original one, anything else is passed to ffmpeg as encoder
+	Width          int           `yaml:"width"`           // Width of the output in pixels, 0 keeps the original size
+	Height         int           `yaml:"height"`          // Height of the output in pixels, 0 keeps the original size
+	Bitrate        string        `yaml:"bitrate"`         // Target video bitrate in ffmpeg format, like "800k"
+	Framerate      int           `yaml:"framerate"`       // Output framerate, 0 keeps the original one
+	Audio          *bool         `yaml:"audio"`           // Option for enabling audio, falls back to the global setting
+	KeepFiles      *bool         `yaml:"keep_files"`      // Option for not deleting files, falls back to the global setting
+	Dash           *bool         `yaml:"dash"`            // Option for emitting MPEG-DASH next to HLS, falls back to the global setting
+	SegmentLength  time.Duration `yaml:"segment_length"`  // Length of each HLS segment
+	ListSize       int           `yaml:"list_size"`       // Number of segments listed in the HLS playlist
+	StartupTimeout time.Duration `yaml:"startup_timeout"` // Time to wait for the first playlist before giving up on the stream
+	Bandwidth      string        `yaml:"bandwidth"`       // Bandwidth advertised for the main output in the master playlist, defaults to the bitrate
+	Renditions     []Rendition   `yaml:"renditions"`      // Additional qualities of the stream for adaptive bitrate playback
+	LowLatency     bool          `yaml:"low_latency"`     // Option for serving low latency HLS with partial segments
+	PartLength     time.Duration `yaml:"part_length"`     // Length of each partial segment in low latency mode
+	DVRWindow      time.Duration `yaml:"dvr_window"`      // Duration of segments kept for timeshift playback, 0 turns it off
+	IdleTimeout    time.Duration `yaml:"idle_timeout"`    // Time without viewers before the stream is stopped, falls back to the global setting
+}
+
+// DVRSegments returns how many segments are kept on top of the list size to cover the DVR window
+func (p Profile) DVRSegments() int {
+	if p.DVRWindow <= 0 || p.SegmentLength <= 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(p.DVRWindow) / float64(p.SegmentLength)))
+}
+
+// PartsPerSegment returns how many partial segments make up a full segment in low latency mode
+func (p Profile) PartsPerSegment() int {
+	if p.PartLength <= 0 {
+		return 1
+	}
+	parts := int(math.Round(float64(p.SegmentLength) / float64(p.PartLength)))
+	if parts < 1 {
+		return 1
+	}
+	return parts
+}
+
+// Rendition describes an additional quality of an adaptive bitrate stream
+type Rendition struct {
+	Name      string `yaml:"name"`      // Name of the rendition, also used as its directory
+	Width     int    `yaml:"width"`     // Width of the rendition in pixels
+	Height    int    `yaml:"height"`    // Height of the rendition in pixels
+	Bitrate   string `yaml:"bitrate"`   // Target video bitrate in ffmpeg format, like "800k"
+	Framerate int    `yaml:"framerate"` // Framerate of the rendition, 0 keeps the original one
+}
+
+// StreamOptions describes how a single stream is handled
+type StreamOptions struct {
+	Profile     string   `yaml:"profile" json:"profile,omitempty"`           // Name of the transcoding profile
+	Record      bool     `yaml:"record" json:"record,omitempty"`             // Option for recording the stream to disk
+	AlwaysOn    bool     `yaml:"always_on" json:"always_on,omitempty"`       // Option for keeping the stream running without viewers
+	IdleTimeout Duration `yaml:"idle_timeout" json:"idle_timeout,omitempty"` // Time without viewers before the stream is stopped, falls back to the profile
+}
+
+// Duration is a time.Duration written in the format of time.ParseDuration, like "30s"
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes the duration from string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// UnmarshalYAML decodes the duration the same way as time.Duration values
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value time.Duration
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// Profile returns the transcoding profile registered under the given name.
+// Empty name returns the default profile. Unset values are taken from the global settings.
+func (s *Specification) Profile(name string) (Profile, error) {
+	profile := Profile{}
+	if name != "" {
+		p, ok := s.Profiles[name]
+		if !ok {
+			return profile, fmt.Errorf("Unknown profile: %s", name)
+		}
+		profile = p
+	}
+	if profile.Codec == "" {
+		profile.Codec = "copy"
+	}
+	if profile.Audio == nil {
+		audio := s.Audio
+		profile.Audio = &audio
+	}
+	if profile.KeepFiles == nil {
+		keepFiles := s.KeepFiles
+		profile.KeepFiles = &keepFiles
+	}
+	if profile.Dash == nil {
+		dash := s.Dash
+		profile.Dash = &dash
+	}
+	if profile.SegmentLength <= 0 {
+		profile.SegmentLength = time.Second
+	}
+	if profile.ListSize <= 0 {
+		profile.ListSize = 3
+	}
+	if profile.PartLength <= 0 {
+		profile.PartLength = 500 * time.Millisecond
+	}
+	if profile.StartupTimeout <= 0 {
+		profile.StartupTimeout = 25 * time.Second
+	}
+	if profile.IdleTimeout <= 0 {
+		profile.IdleTimeout = s.IdleTimeout
+	}
+	return profile, nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestProfile(t *testing.T) {
+	spec := &Specification{}
+	spec.Audio = true
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+profiles:
+  mobile:
+    codec: libx264
+    height: 360
+    bitrate: 400k
+    audio: false
+    segment_length: 2s
+    startup_timeout: 40s
+listen:
+  - alias: camera
+    uri: rtsp://host/1
+    enabled: true
+    profile: mobile
+`), &spec.EndpointYML))
+	assert.Equal(t, "mobile", spec.Listen[0].Profile)
+
+	profile, err := spec.Profile("")
+	assert.Nil(t, err)
+	assert.Equal(t, "copy", profile.Codec)
+	assert.True(t, *profile.Audio)
+	assert.Equal(t, 25*time.Second, profile.StartupTimeout)
+
+	profile, err = spec.Profile("mobile")
+	assert.Nil(t, err)
+	assert.Equal(t, "libx264", profile.Codec)
+	assert.Equal(t, 360, profile.Height)
+	assert.False(t, *profile.Audio)
+	assert.Equal(t, 2*time.Second, profile.SegmentLength)
+	assert.Equal(t, 3, profile.ListSize)
+	assert.Equal(t, 40*time.Second, profile.StartupTimeout)
+
+	_, err = spec.Profile("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestDVRSegments(t *testing.T) {
+	assert.Equal(t, 0, Profile{SegmentLength: time.Second}.DVRSegments())
+	assert.Equal(t, 300, Profile{SegmentLength: 2 * time.Second, DVRWindow: 10 * time.Minute}.DVRSegments())
+	assert.Equal(t, 4, Profile{SegmentLength: 2 * time.Second, DVRWindow: 7 * time.Second}.DVRSegments())
+}
+
+func TestDuration(t *testing.T) {
+	opts := StreamOptions{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"idle_timeout":"30s"}`), &opts))
+	assert.Equal(t, Duration(30*time.Second), opts.IdleTimeout)
+	b, err := json.Marshal(opts)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"idle_timeout":"30s"}`, string(b))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"idle_timeout":"soon"}`), &opts))
+
+	opts = StreamOptions{}
+	assert.Nil(t, yaml.Unmarshal([]byte(`idle_timeout: 1m`), &opts))
+	assert.Equal(t, Duration(time.Minute), opts.IdleTimeout)
+
+	spec := &Specification{}
+	spec.IdleTimeout = time.Minute
+	profile, err := spec.Profile("")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Minute, profile.IdleTimeout)
+}
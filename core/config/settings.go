@@ -0,0 +1,186 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configKey is the key of the setting holding the path of the yml file, it cannot be set from the file itself
+const configKey = "CONFIG"
+
+// field describes a setting of the specification that can be set from
+// env variables, flags and the settings section of the yml file
+type field struct {
+	key   string // name of the env variable without the prefix
+	value reflect.Value
+}
+
+// flag returns the name of the command line flag of the setting
+func (f field) flag() string {
+	return strings.Replace(strings.ToLower(f.key), "_", "-", -1)
+}
+
+// yamlKey returns the key of the setting in the settings section of the yml file
+func (f field) yamlKey() string {
+	return strings.ToLower(f.key)
+}
+
+// fields returns every setting of the specification
+func fields(s *Specification) []field {
+	return structFields(reflect.ValueOf(s).Elem(), nil)
+}
+
+// structFields collects the fields with an env variable name of the struct and its embedded structs
+func structFields(v reflect.Value, fields []field) []field {
+	for i := 0; i < v.NumField(); i++ {
+		t := v.Type().Field(i)
+		if key := t.Tag.Get("envconfig"); key != "" {
+			fields = append(fields, field{key: key, value: v.Field(i)})
+			continue
+		}
+		if t.Anonymous && t.Type.Kind() == reflect.Struct {
+			fields = structFields(v.Field(i), fields)
+		}
+	}
+	return fields
+}
+
+// flagValue records the value of a command line flag, so it can be applied after the other sources
+type flagValue struct {
+	key    string
+	isBool bool
+	values map[string]string
+}
+
+// String returns the recorded value
+func (f *flagValue) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return f.values[f.key]
+}
+
+// Set records the value
+func (f *flagValue) Set(value string) error {
+	f.values[f.key] = value
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be used as flags without value
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// parseFlags returns the values of the flags given in args keyed by the env variable name of their setting
+func parseFlags(s *Specification, args []string) (map[string]string, error) {
+	fs := flag.NewFlagSet("rtsp-stream", flag.ContinueOnError)
+	values := map[string]string{}
+	for _, f := range fields(s) {
+		value := &flagValue{key: f.key, isBool: f.value.Kind() == reflect.Bool, values: values}
+		fs.Var(value, f.flag(), fmt.Sprintf("overrides %s_%s", envPrefix, f.key))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+	return values, nil
+}
+
+// envSet shows if the setting is given as env variable, with or without the prefix
+func envSet(key string) bool {
+	if _, ok := os.LookupEnv(envPrefix + "_" + key); ok {
+		return true
+	}
+	_, ok := os.LookupEnv(key)
+	return ok
+}
+
+// apply sets the values of the yml file not given as env variables, then the values of the flags
+func (s *Specification) apply(settings map[string]interface{}, flags map[string]string) error {
+	var problems Errors
+	for _, f := range fields(s) {
+		value, ok := flags[f.key]
+		source := "--" + f.flag()
+		if !ok {
+			v, inYML := settings[f.yamlKey()]
+			if !inYML || f.key == configKey || envSet(f.key) {
+				continue
+			}
+			value, source = ymlString(v), "settings."+f.yamlKey()
+		}
+		if err := setValue(f.value, value); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %s", source, err))
+		}
+	}
+	return problems.err()
+}
+
+// ymlString returns the value of the yml file in the format of env variables
+func ymlString(v interface{}) string {
+	list, ok := v.([]interface{})
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	items := make([]string, len(list))
+	for i, item := range list {
+		items[i] = fmt.Sprint(item)
+	}
+	return strings.Join(items, ",")
+}
+
+// setValue parses the value into the field the same way env variables are parsed
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(value, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// settingKeys returns the keys accepted in the settings section of the yml file
+func settingKeys() map[string]bool {
+	keys := map[string]bool{}
+	for _, f := range fields(&Specification{}) {
+		if f.key != configKey {
+			keys[f.yamlKey()] = true
+		}
+	}
+	return keys
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "settings")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "custom.yml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`
+settings:
+  port: 9000
+  blacklist_limit: 3
+  blacklist_time: 5m
+  cors_allowed_origins:
+    - http://a.com
+    - http://b.com
+  store_dir: `+filepath.Join(dir, "videos")+`
+  persist_dir: `+filepath.Join(dir, "data")+`
+`), 0644))
+	os.Setenv("RTSP_STREAM_BLACKLIST_LIMIT", "5")
+	defer os.Unsetenv("RTSP_STREAM_BLACKLIST_LIMIT")
+
+	s, err := Load([]string{"--config", path, "--port", "9100", "--cors-enabled"})
+	assert.Nil(t, err)
+	assert.Equal(t, path, s.ConfigFile)
+	assert.Equal(t, 9100, s.Port)
+	assert.True(t, s.CORS.Enabled)
+	assert.Equal(t, 5, s.BlacklistLimit)
+	assert.Equal(t, 5*time.Minute, s.BlacklistTime)
+	assert.Equal(t, []string{"http://a.com", "http://b.com"}, s.AllowedOrigins)
+	assert.Equal(t, 2*time.Minute, s.CleanupTime)
+}
+
+func TestLoadInvalidSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "settings")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rtsp-stream.yml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`
+settings:
+  prot: 9000
+  config: other.yml
+`), 0644))
+	_, err = Load([]string{"--config", path})
+	assert.NotNil(t, err)
+	assert.Len(t, err.(Errors), 2)
+
+	_, err = Load([]string{"--config", filepath.Join(dir, "missing.yml"), "--cleanup-time", "soon"})
+	assert.NotNil(t, err)
+	_, err = Load([]string{"--config", filepath.Join(dir, "missing.yml"), "extra"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Errors describes every problem found in the configuration
+type Errors []error
+
+// Error joins the problems into a single message
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add appends the problems of err, flattening Errors
+func (e Errors) add(err error) Errors {
+	if err == nil {
+		return e
+	}
+	if errs, ok := err.(Errors); ok {
+		return append(e, errs...)
+	}
+	return append(e, err)
+}
+
+// err returns nil if there are no problems
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate checks if the settings of the yml file are consistent
+func (e EndpointYML) Validate() error {
+	var problems Errors
+	aliases := map[string]bool{}
+	for i, item := range e.Listen {
+		if err := validURI(item.Uri); err != nil {
+			problems = append(problems, fmt.Errorf("listen[%d]: %s", i, err))
+		}
+		if item.Alias == "" {
+			problems = append(problems, fmt.Errorf("listen[%d]: alias is missing", i))
+		} else if aliases[item.Alias] {
+			problems = append(problems, fmt.Errorf("listen[%d]: alias %s is used more than once", i, item.Alias))
+		}
+		aliases[item.Alias] = true
+		if _, ok := e.Profiles[item.Profile]; item.Profile != "" && !ok {
+			problems = append(problems, fmt.Errorf("listen[%d]: unknown profile %s", i, item.Profile))
+		}
+	}
+	for _, name := range EndpointNames {
+		setting, _ := e.Endpoints.Setting(name)
+		for _, carrier := range setting.Carriers {
+			if carrier != CarrierHeader && carrier != CarrierCookie && carrier != CarrierQuery {
+				problems = append(problems, fmt.Errorf("endpoints.%s: unknown token carrier %s", name, carrier))
+			}
+		}
+	}
+	keys := settingKeys()
+	var unknown []string
+	for key := range e.Settings {
+		if !keys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problems = append(problems, fmt.Errorf("settings: unknown key %s", key))
+	}
+	names := []string{}
+	for name := range e.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// renditions are ranked by players by the bandwidth advertised in the master playlist
+		for i, rendition := range e.Profiles[name].Renditions {
+			if !validBitrate(rendition.Bitrate) {
+				problems = append(problems, fmt.Errorf("profiles.%s.renditions[%d]: bitrate %q is missing or invalid", name, i, rendition.Bitrate))
+			}
+		}
+	}
+	for i, hook := range e.Webhooks {
+		u, err := url.Parse(hook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Errorf("webhooks[%d]: invalid url %q", i, hook.URL))
+		}
+	}
+	return problems.err()
+}
+
+// validBitrate shows if the bitrate is a positive value in ffmpeg format, like "800k" or "2M"
+func validBitrate(bitrate string) bool {
+	value := strings.TrimRight(bitrate, "kKmM")
+	if len(bitrate)-len(value) > 1 {
+		return false
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	return err == nil && parsed > 0
+}
+
+// Validate checks the settings read from env variables
+func (s *Specification) Validate() error {
+	var problems Errors
+	if s.Port < 1 || s.Port > 65535 {
+		problems = append(problems, fmt.Errorf("PORT: %d is not a valid port", s.Port))
+	}
+	switch strings.ToLower(s.JWTMethod) {
+	case "secret":
+	case "rsa":
+		if !s.JWTEnabled {
+			break
+		}
+		if f, err := os.Open(s.JWTPubKeyPath); err != nil {
+			problems = append(problems, fmt.Errorf("AUTH_JWT_PUB_PATH: %s", err))
+		} else {
+			f.Close()
+		}
+	case "jwks":
+		if !s.JWTEnabled {
+			break
+		}
+		if s.JWKSURL == "" {
+			problems = append(problems, fmt.Errorf("AUTH_JWT_JWKS_URL: missing for the jwks method"))
+		} else if strings.Contains(s.JWKSURL, "://") {
+			if err := validURI(s.JWKSURL); err != nil {
+				problems = append(problems, fmt.Errorf("AUTH_JWT_JWKS_URL: %s", err))
+			}
+		} else if f, err := os.Open(s.JWKSURL); err != nil {
+			problems = append(problems, fmt.Errorf("AUTH_JWT_JWKS_URL: %s", err))
+		} else {
+			f.Close()
+		}
+	default:
+		problems = append(problems, fmt.Errorf("AUTH_JWT_METHOD: unknown method %s", s.JWTMethod))
+	}
+	if s.LimitPolicy != "reject" && s.LimitPolicy != "evict" {
+		problems = append(problems, fmt.Errorf("LIMIT_POLICY: unknown policy %s", s.LimitPolicy))
+	}
+	if err := writable(s.StoreDir); err != nil {
+		problems = append(problems, fmt.Errorf("STORE_DIR: %s", err))
+	}
+	if s.PersistEnabled {
+		if err := writable(s.PersistDir); err != nil {
+			problems = append(problems, fmt.Errorf("PERSIST_DIR: %s", err))
+		}
+	}
+	return problems.err()
+}
+
+// validURI checks if the stream URI has a scheme and a host
+func validURI(uri string) error {
+	if uri == "" {
+		return fmt.Errorf("uri is missing")
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("invalid uri: %s", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid uri %q, scheme and host are required", uri)
+	}
+	return nil
+}
+
+// writable checks if files can be created in the directory,
+// or in its closest existing parent if it does not exist yet
+func writable(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			f, err := ioutil.TempFile(dir, ".check")
+			if err != nil {
+				return err
+			}
+			f.Close()
+			return os.Remove(f.Name())
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+}
+
+// unknownEnv returns a problem for every variable with the prefix of the application
+// that is not read into the specification, typos would be ignored otherwise
+func unknownEnv(environ []string) Errors {
+	known := map[string]bool{}
+	for _, f := range fields(&Specification{}) {
+		known[envPrefix+"_"+f.key] = true
+	}
+	var problems Errors
+	for _, item := range environ {
+		key := strings.SplitN(item, "=", 2)[0]
+		if strings.HasPrefix(key, envPrefix+"_") && !known[key] {
+			problems = append(problems, fmt.Errorf("%s: unknown environment variable", key))
+		}
+	}
+	return problems
+}
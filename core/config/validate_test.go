@@ -0,0 +1,121 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		Input EndpointYML
+		Valid bool
+	}{
+		{
+			Input: EndpointYML{Listen: []ListenSetting{{Uri: "rtsp://host/1", Alias: "one"}, {Uri: "rtsp://host/2", Alias: "two"}}},
+			Valid: true,
+		},
+		{
+			Input: EndpointYML{Listen: []ListenSetting{{Uri: "rtsp://host/1"}}},
+			Valid: false,
+		},
+		{
+			Input: EndpointYML{Listen: []ListenSetting{{Alias: "camera"}}},
+			Valid: false,
+		},
+		{
+			Input: EndpointYML{Listen: []ListenSetting{{Uri: "host/1"}}},
+			Valid: false,
+		},
+		{
+			Input: EndpointYML{Listen: []ListenSetting{{Uri: "rtsp://host/1", Alias: "camera"}, {Uri: "rtsp://host/2", Alias: "camera"}}},
+			Valid: false,
+		},
+		{
+			Input: EndpointYML{Listen: []ListenSetting{{Uri: "rtsp://host/1", StreamOptions: StreamOptions{Profile: "mobile"}}}},
+			Valid: false,
+		},
+		{
+			Input: EndpointYML{
+				Listen:   []ListenSetting{{Uri: "rtsp://host/1", Alias: "camera", StreamOptions: StreamOptions{Profile: "mobile"}}},
+				Profiles: map[string]Profile{"mobile": {}},
+			},
+			Valid: true,
+		},
+		{
+			Input: EndpointYML{Profiles: map[string]Profile{"abr": {Renditions: []Rendition{{Name: "360p", Height: 360, Bitrate: "800k"}}}}},
+			Valid: true,
+		},
+		{
+			Input: EndpointYML{Profiles: map[string]Profile{"abr": {Renditions: []Rendition{{Name: "360p", Height: 360}}}}},
+			Valid: false,
+		},
+		{
+			Input: EndpointYML{Profiles: map[string]Profile{"abr": {Renditions: []Rendition{{Name: "360p", Height: 360, Bitrate: "0k"}}}}},
+			Valid: false,
+		},
+		{
+			Input: EndpointYML{Webhooks: []Webhook{{Secret: "secret"}}},
+			Valid: false,
+		},
+		{
+			Input: EndpointYML{Endpoints: Endpoints{Static: EndpointSetting{Carriers: []string{"cookie", "query", "header"}}}},
+			Valid: true,
+		},
+		{
+			Input: EndpointYML{Endpoints: Endpoints{Login: EndpointSetting{Carriers: []string{"body"}}}},
+			Valid: false,
+		},
+		{
+			Input: EndpointYML{Webhooks: []Webhook{{URL: "https://host/hook"}}},
+			Valid: true,
+		},
+	}
+	for i, testCase := range tt {
+		assert.Equal(t, testCase.Valid, testCase.Input.Validate() == nil, "testcase %d", i)
+	}
+}
+
+func TestValidateSpecification(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	assert.Nil(t, ioutil.WriteFile(file, nil, 0644))
+
+	s := &Specification{Port: 8080}
+	s.JWTMethod = "secret"
+	s.LimitPolicy = "reject"
+	s.StoreDir = filepath.Join(dir, "videos", "new")
+	assert.Nil(t, s.Validate())
+
+	s.Port = 0
+	s.JWTEnabled = true
+	s.JWTMethod = "rsa"
+	s.JWTPubKeyPath = filepath.Join(dir, "missing.pub")
+	s.LimitPolicy = "drop"
+	s.StoreDir = file
+	err = s.Validate()
+	assert.NotNil(t, err)
+	assert.Len(t, err.(Errors), 4)
+
+	s = &Specification{Port: 8080}
+	s.JWTEnabled = true
+	s.JWTMethod = "jwks"
+	s.LimitPolicy = "evict"
+	s.StoreDir = dir
+	assert.NotNil(t, s.Validate())
+	s.JWKSURL = filepath.Join(dir, "missing.json")
+	assert.NotNil(t, s.Validate())
+	s.JWKSURL = "https://id.example.com/.well-known/jwks.json"
+	assert.Nil(t, s.Validate())
+}
+
+func TestUnknownEnv(t *testing.T) {
+	problems := unknownEnv([]string{"RTSP_STREAM_PORT=8080", "RTSP_STREAM_CORS_ENABLED=true", "RTSP_STREAM_PROT=8080", "PATH=/bin"})
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Error(), "RTSP_STREAM_PROT")
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Watch calls reload whenever the file at the given path changes or the application receives SIGHUP.
+// The file is checked in the given interval, 0 only listens for the signal. Watch blocks forever.
+func Watch(path string, interval time.Duration, reload func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	last := modified(path)
+	for {
+		select {
+		case <-hup:
+			last = modified(path)
+			reload()
+		case <-tick:
+			current := modified(path)
+			if current.Equal(last) {
+				continue
+			}
+			last = current
+			reload()
+		}
+	}
+}
+
+// modified returns the modification time of the file, zero time if it does not exist
+func modified(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
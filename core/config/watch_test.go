@@ -0,0 +1,37 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rtsp-stream.yml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("version: 1"), 0644))
+
+	reloaded := make(chan struct{}, 1)
+	go Watch(path, 10*time.Millisecond, func() {
+		select {
+		case reloaded <- struct{}{}:
+		default:
+		}
+	})
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, reloaded, 0)
+
+	later := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(path, later, later))
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("change is not noticed")
+	}
+}
@@ -12,12 +12,24 @@ import (
 	"os/signal"
 	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/Roverr/rtsp-stream/core/auth"
 	"github.com/Roverr/rtsp-stream/core/blacklist"
 	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/events"
+	"github.com/Roverr/rtsp-stream/core/limits"
+	"github.com/Roverr/rtsp-stream/core/metrics"
+	"github.com/Roverr/rtsp-stream/core/recording"
+	"github.com/Roverr/rtsp-stream/core/registry"
+	"github.com/Roverr/rtsp-stream/core/snapshot"
+	"github.com/Roverr/rtsp-stream/core/store"
+	"github.com/Roverr/rtsp-stream/core/supervisor"
+	"github.com/Roverr/rtsp-stream/core/viewers"
+	"github.com/Roverr/rtsp-stream/core/webhook"
 	"github.com/julienschmidt/httprouter"
 	"github.com/riltech/streamer"
 	"github.com/sirupsen/logrus"
@@ -33,6 +45,7 @@ var ErrTimeout = errors.New("Timeout error")
 type StreamDTO struct {
 	URI   string `json:"uri"`
 	Alias string `json:"alias"`
+	config.StreamOptions
 }
 
 // StopDTO describes a DTO for the /remove and /stop endpoints
@@ -45,10 +58,32 @@ type StopDTO struct {
 
 // SummariseDTO describes each stream and their state of running
 type SummariseDTO struct {
-	Running bool   `json:"running"`
-	URI     string `json:"uri"`
-	ID      string `json:"id"`
-	Alias   string `json:"alias"`
+	Running   bool                 `json:"running"`
+	URI       string               `json:"uri"`
+	MasterURI string               `json:"master_uri,omitempty"`
+	DashURI   string               `json:"dash_uri,omitempty"`
+	DVRURI    string               `json:"dvr_uri,omitempty"`
+	ID        string               `json:"id"`
+	Alias     string               `json:"alias"`
+	AlwaysOn  bool                 `json:"always_on,omitempty"`
+	Viewers   int                  `json:"viewers"`
+	Restarts  []supervisor.Restart `json:"restarts,omitempty"`
+	Expires   *time.Time           `json:"expires,omitempty"`
+}
+
+// LimitsDTO describes the limits and their usage, listed by /list on request
+type LimitsDTO struct {
+	Running int    `json:"running"`
+	Max     int    `json:"max,omitempty"`
+	PerUser int    `json:"per_user,omitempty"`
+	Owned   *int   `json:"owned,omitempty"` // Running streams started by the caller, only present with a per user quota
+	Policy  string `json:"policy,omitempty"`
+}
+
+// ListDTO describes the streams together with the limits, returned by /list?limits=true
+type ListDTO struct {
+	Streams []*SummariseDTO `json:"streams"`
+	Limits  LimitsDTO       `json:"limits"`
 }
 
 // IController describes main functions for the controller
@@ -63,20 +98,47 @@ type IController interface {
 	StartStreamHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)       // handler - POST /start
 	StaticFileHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)        // handler - GET /stream/{id}/{file}
 	StopStreamHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)        // handler - POST /stop
+	MetricsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)           // handler - GET /metrics
+	ListRecordingsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)    // handler - GET /recordings
+	RecordingFileHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)     // handler - GET /recordings/{id}/{file}
+	SnapshotHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)          // handler - GET /snapshot/{id}.jpg
+	EventsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)            // handler - GET /events
+	GetListenHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)         // handler - GET /listen/{alias}
+	PutListenHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)         // handler - PUT /listen/{alias}
+	DeleteListenHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)      // handler - DELETE /listen/{alias}
+	SignHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)              // handler - GET /sign/{id}
+	LoginHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)             // handler - POST /login
+	LogoutHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)            // handler - DELETE /login
+	Instrument(route string, handle httprouter.Handle) httprouter.Handle                  // observes latency of a route
+	Enabled(endpoint string, handle httprouter.Handle) httprouter.Handle                  // serves the route only while the endpoint is enabled
+	Reload(yml config.EndpointYML)                                                        // applies a changed yml configuration
 	ExitPreHook() chan bool                                                               // runs before the application exits to clean up
 }
 
 // Controller holds all handler functions for the API
 type Controller struct {
-	spec       *config.Specification
-	streams    map[string]*streamer.Stream
-	index      map[string]string
-	alias      map[string]string
-	preload    map[string]string
-	blacklist  blacklist.IList
-	fileServer http.Handler
-	timeout    time.Duration
-	jwt        auth.JWT
+	spec            *config.Specification
+	endpoints       *atomic.Value
+	registry        registry.IRegistry
+	blacklist       blacklist.IList
+	metrics         metrics.IMetrics
+	supervisor      supervisor.ISupervisor
+	events          events.IBus
+	store           store.IStore
+	janitor         recording.IJanitor
+	snapshots       snapshot.ICache
+	viewers         viewers.ITracker
+	limiter         limits.ILimiter
+	persistMu       *sync.Mutex
+	listenMu        *sync.Mutex
+	listen          map[string]config.ListenSetting // listen entries registered via the API
+	ymlListen       []config.ListenSetting          // listen entries of the yml file
+	fileServer      http.Handler
+	recordingServer http.Handler
+	timeout         time.Duration
+	jwt             auth.JWT
+	policy          auth.IPolicy
+	signer          auth.ISigner
 }
 
 // Type check
@@ -84,43 +146,151 @@ var _ IController = (*Controller)(nil)
 
 // NewController creates a new instance of Controller
 func NewController(spec *config.Specification, fileServer http.Handler) *Controller {
-	provider, err := auth.NewJWTProvider(spec.Auth)
-	if err != nil {
-		logrus.Fatal("Could not create new JWT provider: ", err)
+	var provider auth.JWT
+	if spec.JWTEnabled {
+		p, err := auth.NewProvider(spec.Auth)
+		if err != nil {
+			logrus.Fatal("Could not create new JWT provider: ", err)
+		}
+		provider = p
 	}
 	ctrl := &Controller{
-		spec,
-		map[string]*streamer.Stream{},
-		map[string]string{},
-		map[string]string{},
-		map[string]string{},
-		(*blacklist.List)(nil),
-		fileServer,
-		time.Second * 15,
-		provider,
+		spec:            spec,
+		endpoints:       &atomic.Value{},
+		registry:        registry.NewRegistry(),
+		blacklist:       (*blacklist.List)(nil),
+		metrics:         (*metrics.Metrics)(nil),
+		supervisor:      (*supervisor.Supervisor)(nil),
+		events:          events.NewBus(),
+		store:           (*store.FileStore)(nil),
+		janitor:         recording.NewJanitor(spec.RecordDir, spec.RecordRetention, int64(spec.RecordQuota)*1024*1024, spec.RecordSegmentLength),
+		snapshots:       snapshot.NewCache(spec.SnapshotTime),
+		viewers:         viewers.NewTracker(spec.ViewerTimeout),
+		limiter:         (*limits.Limiter)(nil),
+		persistMu:       &sync.Mutex{},
+		listenMu:        &sync.Mutex{},
+		listen:          map[string]config.ListenSetting{},
+		ymlListen:       spec.Listen,
+		fileServer:      fileServer,
+		recordingServer: http.FileServer(http.Dir(spec.RecordDir)),
+		timeout:         time.Second * 15,
+		jwt:             provider,
+		policy:          newPolicy(),
+		signer:          (*auth.Signer)(nil),
+	}
+	if spec.SignSecret != "" {
+		ctrl.signer = auth.NewSigner(spec.SignSecret, spec.SignExpiry)
+	}
+	ctrl.endpoints.Store(spec.Endpoints)
+	if len(spec.Webhooks) > 0 {
+		ch, _ := ctrl.events.Subscribe()
+		go webhook.NewDispatcher(spec.Webhooks, spec.WebhookDelivery).Run(ch)
 	}
 	if spec.BlacklistEnabled {
-		ctrl.blacklist = blacklist.NewList(spec.BlacklistTime, spec.BlacklistLimit)
+		ctrl.blacklist = blacklist.NewList(spec.BlacklistTime, spec.BlacklistLimit).OnBan(ctrl.banned)
 	}
-	if spec.CleanupEnabled {
+	if spec.MaxStreams > 0 || spec.MaxStreamsPerUser > 0 {
+		ctrl.limiter = limits.NewLimiter(spec.MaxStreams, spec.MaxStreamsPerUser, spec.LimitPolicy)
+	}
+	// collected even while the endpoint is disabled, so reloading the configuration can turn it on
+	ctrl.metrics = metrics.NewMetrics(ctrl.registry, ctrl.blacklist, ctrl.limiter)
+	if spec.SupervisorEnabled {
+		backoff := supervisor.Backoff{Min: spec.RestartMinBackoff, Max: spec.RestartMaxBackoff}
+		ctrl.supervisor = supervisor.NewSupervisor(spec.StallTimeout, backoff, ctrl.recoverStream).OnFailure(ctrl.failed)
+	} else {
+		// exits of the transcoding process are still published, the streams are left stopped
+		ctrl.supervisor = supervisor.NewSupervisor(0, supervisor.Backoff{}, nil).OnFailure(ctrl.failed)
+	}
+	go func() {
+		for {
+			<-time.After(spec.CleanupTime)
+			if spec.CleanupEnabled {
+				ctrl.stopInactiveStreams()
+			}
+			ctrl.startAlwaysOn()
+		}
+	}()
+	// directories of the next day are created independently of the cleanup, Prepare covers a whole day ahead
+	go func() {
+		for {
+			<-time.After(time.Hour)
+			ctrl.prepareRecordings()
+		}
+	}()
+	if spec.RecordCleanupTime > 0 {
 		go func() {
 			for {
-				<-time.After(spec.CleanupTime)
-				ctrl.stopInactiveStreams()
+				<-time.After(spec.RecordCleanupTime)
+				ctrl.cleanRecordings()
 			}
 		}()
 	}
 
-	// retain preloads
-	for _, item := range spec.EndpointYML.Listen {
-		if item.Enabled {
-			ctrl.preload[item.Alias] = item.Uri
+	if spec.PersistEnabled {
+		fileStore, err := store.NewFileStore(spec.PersistDir)
+		if err != nil {
+			logrus.Fatal("Could not create stream store: ", err)
 		}
+		ctrl.store = fileStore
+		ctrl.restore()
 	}
+	// listen entries are only changed under listenMu, startup included
+	ctrl.listenMu.Lock()
+	if spec.PersistEnabled {
+		ctrl.restoreListen()
+	}
+	ctrl.syncPreloads()
+	ctrl.listenMu.Unlock()
 
 	return ctrl
 }
 
+// restore re-registers the streams kept in the store with their original IDs and aliases
+// and starts the ones that were running before the application stopped
+func (c *Controller) restore() {
+	records, err := c.store.Load()
+	if err != nil {
+		logrus.Errorf("Could not load stored streams: %s | Store", err)
+		return
+	}
+	for _, record := range records {
+		stream := c.newStream(record.URI, record.ID, record.StreamOptions)
+		c.registry.SetOptions(stream.ID, record.StreamOptions)
+		c.registry.Add(stream, record.Alias)
+		logrus.Infof("%s is restored as %s | Store", record.URI, record.ID)
+		if record.Running {
+			go func(record store.Record) {
+				start := func() { c.startStream(record.URI, record.Alias, record.StreamOptions) }
+				if err := c.startAdmitted(record.URI, start); err != nil {
+					logrus.Infof("%s could not be restarted: %s | Store", record.ID, err)
+				}
+			}(record)
+		}
+	}
+}
+
+// persist saves the current state of the registry into the store
+func (c *Controller) persist() {
+	c.persistMu.Lock()
+	defer c.persistMu.Unlock()
+	records := []store.Record{}
+	for id, stream := range c.registry.Streams() {
+		stream.Mux.Lock()
+		running := stream.Running
+		stream.Mux.Unlock()
+		records = append(records, store.Record{
+			ID:            id,
+			URI:           stream.OriginalURI,
+			Alias:         c.registry.AliasOf(id),
+			Running:       running,
+			StreamOptions: c.registry.Options(id),
+		})
+	}
+	if err := c.store.Save(records); err != nil {
+		logrus.Errorf("Could not save streams: %s | Store", err)
+	}
+}
+
 // marshalValidateURI is for validiting that the URI is in a valid format
 // and marshaling it into the dto pointer
 func (c *Controller) marshalValidatedURI(dto *StreamDTO, body io.Reader) error {
@@ -153,40 +323,42 @@ func (c *Controller) isAuthenticated(r *http.Request, endpoint string) bool {
 	if !c.spec.JWTEnabled {
 		return true
 	}
-	token, claims := c.jwt.Validate(r.Header.Get("Authorization"))
-	if token == nil || !token.Valid {
-		return false
+	return c.policy.Allows(endpoint, c.endpoint(endpoint), c.claims(r, endpoint))
+}
+
+// claims returns the claims of the valid token of the request to the endpoint,
+// nil if there is none or authentication is disabled
+func (c *Controller) claims(r *http.Request, endpoint string) *auth.Claim {
+	if !c.spec.JWTEnabled {
+		return nil
 	}
-	switch endpoint {
-	case "list":
-		if c.spec.Endpoints.List.Secret == "" {
-			return true
-		}
-		return claims.Secret == c.spec.Endpoints.List.Secret
-	case "start":
-		if c.spec.Endpoints.Start.Secret == "" {
-			return true
-		}
-		return claims.Secret == c.spec.Endpoints.Start.Secret
-	case "stop":
-		if c.spec.Endpoints.Stop.Secret == "" {
-			return true
-		}
-		return claims.Secret == c.spec.Endpoints.Stop.Secret
-	case "static":
-		if c.spec.Endpoints.Static.Secret == "" {
-			return true
-		}
-		return claims.Secret == c.spec.Endpoints.Static.Secret
+	token, claims := c.jwt.Validate(c.token(r, endpoint))
+	if token == nil || !token.Valid {
+		return nil
 	}
-	return true
+	return claims
+}
+
+// newPolicy creates the table of scopes required by the endpoints
+func newPolicy() *auth.Policy {
+	return auth.NewPolicy().
+		Register("start", auth.ScopeStart).
+		Register("stop", auth.ScopeStop).
+		Register("list", auth.ScopeList).
+		Register("static", auth.ScopeView).
+		Register("snapshot", auth.ScopeView).
+		Register("listen", auth.ScopeListen).
+		Register("sign", auth.ScopeView).
+		Register("recordings", auth.ScopeRecordings).
+		Register("events", auth.ScopeEvents).
+		Register("metrics", auth.ScopeMetrics)
 }
 
 // stopInactiveStreams is for stopping all transcoding for streams that are not watched anymore
 func (c *Controller) stopInactiveStreams() {
-	for name, stream := range c.streams {
-		// If the streak is active, there is no need for stopping
-		if stream.Streak.IsActive() {
+	for name, stream := range c.registry.Streams() {
+		// streams are kept while they have viewers and until they had none for their idle timeout
+		if since, idle := c.viewers.IdleSince(name); !idle || time.Since(since) < c.idleTimeout(name) {
 			logrus.Infof("%s is active. Skipping. | Inactivity cleaning", name)
 			continue
 		}
@@ -194,12 +366,22 @@ func (c *Controller) stopInactiveStreams() {
 			logrus.Debugf("%s is not running. Skipping. | Inactivity cleaning", name)
 			continue
 		}
+		if c.registry.Options(name).AlwaysOn {
+			logrus.Debugf("%s is always on. Skipping. | Inactivity cleaning", name)
+			continue
+		}
 		logrus.Infof("%s is being stopped | Inactivity cleaning", name)
+		c.supervisor.Unwatch(name)
 		if err := stream.Stop(); err != nil {
 			logrus.Error(err)
 		}
+		c.metrics.StreamEvent(metrics.EventStop, name, c.registry.AliasOf(name))
+		c.events.Publish(events.Event{Type: events.Stopped, ID: name, Alias: c.registry.AliasOf(name), URI: stream.OriginalURI, Reason: "inactive"})
+		c.limiter.Remove(name)
+		c.snapshots.Remove(name)
 		logrus.Infof("%s is stopped | Inactivity cleaning", name)
 	}
+	c.persist()
 }
 
 // sendError sends an error to the client
@@ -228,7 +410,17 @@ func (c *Controller) sendStart(w http.ResponseWriter, success bool, stream *stre
 	}
 	URI := fmt.Sprintf("/stream/%s/index.m3u8", name)
 
-	b, _ := json.Marshal(SummariseDTO{URI: URI, Running: true, ID: stream.ID, Alias: alias})
+	dto := SummariseDTO{
+		URI:       URI,
+		MasterURI: c.masterURI(name, c.registry.Options(stream.ID)),
+		DashURI:   c.dashURI(name, c.registry.Options(stream.ID)),
+		DVRURI:    c.dvrURI(name, c.registry.Options(stream.ID)),
+		Running:   true,
+		ID:        stream.ID,
+		Alias:     alias,
+	}
+	c.signDTO(&dto, name, c.signer.Expiry())
+	b, _ := json.Marshal(dto)
 	w.Header().Add("Content-Type", "application/json")
 	w.Write(b)
 }
@@ -240,40 +432,64 @@ func (c *Controller) ListStreamHandler(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 	dto := []*SummariseDTO{}
+	claims := c.claims(r, "list")
 
-	// active streams
-	for key, stream := range c.streams {
-		aliasName := ""
+	// active streams, limited to the ones the caller may access
+	for key, stream := range c.registry.Streams() {
+		aliasName := c.registry.AliasOf(stream.ID)
+		if !c.canAccess(claims, stream.ID, aliasName, stream.OriginalURI) {
+			continue
+		}
 		newKey := key
-		for name, id := range c.alias {
-			if id == stream.ID {
-				aliasName = name
-				newKey = name
-			}
+		if len(aliasName) > 0 {
+			newKey = aliasName
 		}
 		dto = append(dto, &SummariseDTO{
-			URI:     fmt.Sprintf("/stream/%s/index.m3u8", newKey),
-			Running: stream.Streak.IsActive(),
-			ID:      stream.ID,
-			Alias:   aliasName,
+			URI:       fmt.Sprintf("/stream/%s/index.m3u8", newKey),
+			MasterURI: c.masterURI(newKey, c.registry.Options(stream.ID)),
+			DashURI:   c.dashURI(newKey, c.registry.Options(stream.ID)),
+			DVRURI:    c.dvrURI(newKey, c.registry.Options(stream.ID)),
+			Running:   stream.Streak.IsActive(),
+			ID:        stream.ID,
+			Alias:     aliasName,
+			AlwaysOn:  c.registry.Options(stream.ID).AlwaysOn,
+			Viewers:   c.viewers.Count(stream.ID),
+			Restarts:  c.supervisor.History(stream.ID),
 		})
 	}
 
-	// preload streams
-	for name := range c.preload {
+	// preload streams, started ones are listed with their stream
+	for name, setting := range c.registry.Preloads() {
+		if _, started := c.registry.ResolveAlias(name); started {
+			continue
+		}
+		if !c.canAccess(claims, "", name, setting.Uri) {
+			continue
+		}
 		dto = append(dto, &SummariseDTO{
-			URI:     fmt.Sprintf("/stream/%s/index.m3u8", name),
-			Running: false,
-			ID:      "",
-			Alias:   name,
+			URI:       fmt.Sprintf("/stream/%s/index.m3u8", name),
+			MasterURI: c.masterURI(name, setting.StreamOptions),
+			DashURI:   c.dashURI(name, setting.StreamOptions),
+			DVRURI:    c.dvrURI(name, setting.StreamOptions),
+			Running:   false,
+			ID:        "",
+			Alias:     name,
+			AlwaysOn:  setting.AlwaysOn,
 		})
 	}
 
-	b, err := json.Marshal(dto)
+	// the limits are listed next to the streams on request, keeping the plain list for existing clients
+	state := c.limitState(r)
+	var body interface{} = dto
+	if r.URL.Query().Get("limits") == "true" {
+		body = ListDTO{Streams: dto, Limits: state}
+	}
+	b, err := json.Marshal(body)
 	if err != nil {
 		c.sendError(w, ErrUnexpected, http.StatusInternalServerError)
 		return
 	}
+	c.setLimitHeaders(w, state)
 	w.Header().Add("Content-Type", "application/json")
 	w.Write(b)
 }
@@ -305,72 +521,146 @@ func (c *Controller) StopStreamHandler(w http.ResponseWriter, r *http.Request, p
 
 	if len(dto.Alias) > 0 {
 		// redirect alias if used
-		newid, ok := c.alias[dto.Alias]
+		newid, ok := c.registry.ResolveAlias(dto.Alias)
 		if ok {
 			dto.ID = newid
 		}
 	}
 
-	if s, ok := c.streams[dto.ID]; ok {
+	if !c.canAccessRef(r, "stop", dto.ID) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if s, ok := c.registry.Get(dto.ID); ok {
 		logrus.Infof("%s is being stopped | StopStreamHandler", dto.ID)
+		// streams stopped on purpose are not kept running anymore
+		c.setAlwaysOn(dto.ID, false)
+		c.supervisor.Unwatch(dto.ID)
 		err := s.Stop()
 		if err != nil {
 			logrus.Error(err)
 			c.sendError(w, err, http.StatusInternalServerError)
 			return
 		}
+		c.metrics.StreamEvent(metrics.EventStop, dto.ID, c.registry.AliasOf(dto.ID))
+		c.events.Publish(events.Event{Type: events.Stopped, ID: dto.ID, Alias: c.registry.AliasOf(dto.ID), URI: s.OriginalURI, Reason: "request"})
+		// stopped streams count against whoever starts them next and have no image to serve
+		c.limiter.Remove(dto.ID)
+		c.snapshots.Remove(dto.ID)
 		if dto.Remove {
-			delete(c.index, s.OriginalURI)
-			delete(c.streams, dto.ID)
+			c.registry.Remove(dto.ID)
+			c.supervisor.Remove(dto.ID)
+			c.viewers.Remove(dto.ID)
 		}
+		c.persist()
 	}
 	logrus.Debugf("%s is stopped | StopStreamHandler", dto.ID)
 	w.WriteHeader(http.StatusOK)
 }
 
-func (c *Controller) startPreloadStream(Alias string, URI string) {
-	logrus.Debugf("%s is being initialized", URI)
+// startStream starts the transcoding of the given URI or restarts it if it is already known.
+// Concurrent calls for the same URI wait for each other and share a single transcoding process.
+func (c *Controller) startStream(URI string, alias string, opts config.StreamOptions) *streamer.Stream {
+	return c.registry.Start(URI, func() *streamer.Stream {
+		if stream, ok := c.registry.GetByURI(URI); ok {
+			if opts.AlwaysOn {
+				c.setAlwaysOn(stream.ID, true)
+			}
+			if !stream.Running {
+				if c.registry.Options(stream.ID).Record {
+					c.prepareRecording(stream.ID)
+				}
+				stream.Restart().Wait()
+				c.metrics.StreamEvent(metrics.EventRestart, stream.ID, c.registry.AliasOf(stream.ID))
+				event := events.Event{Type: events.Restarted, ID: stream.ID, Alias: c.registry.AliasOf(stream.ID), URI: URI}
+				if !stream.Running {
+					event.Type = events.Timeout
+				}
+				c.events.Publish(event)
+				c.persist()
+			}
+			if stream.Running {
+				c.viewers.Touch(stream.ID)
+				c.supervisor.Watch(stream)
+			}
+			return stream
+		}
 
-	_, knownStream := c.index[URI]
-	if knownStream {
-		return
-	}
+		stream := c.newStream(URI, "", opts)
+		if opts.Record {
+			c.prepareRecording(stream.ID)
+		}
+		stream.Start().Wait()
+		if !stream.Running {
+			c.blacklist.AddOrIncrease(URI)
+			c.metrics.StreamEvent(metrics.EventTimeout, stream.ID, alias)
+			c.events.Publish(events.Event{Type: events.Timeout, ID: stream.ID, Alias: alias, URI: URI})
+			return stream
+		}
+		c.registry.SetOptions(stream.ID, opts)
+		c.registry.Add(stream, alias)
+		c.blacklist.Remove(URI)
+		c.metrics.StreamEvent(metrics.EventStart, stream.ID, alias)
+		c.events.Publish(events.Event{Type: events.Started, ID: stream.ID, Alias: alias, URI: URI})
+		if alias != "" {
+			c.events.Publish(events.Event{Type: events.Aliased, ID: stream.ID, Alias: alias, URI: URI})
+		}
+		c.persist()
+		c.viewers.Touch(stream.ID)
+		c.supervisor.Watch(stream)
+		return stream
+	})
+}
 
-	stream, id := streamer.NewStream(
-		URI,
-		c.spec.StoreDir,
-		c.spec.KeepFiles,
-		c.spec.Audio,
-		streamer.ProcessLoggingOpts{
-			Enabled:    c.spec.ProcessLogging.Enabled,
-			Compress:   c.spec.ProcessLogging.Compress,
-			Directory:  c.spec.ProcessLogging.Directory,
-			MaxAge:     c.spec.ProcessLogging.MaxAge,
-			MaxBackups: c.spec.ProcessLogging.MaxBackups,
-			MaxSize:    c.spec.ProcessLogging.MaxSize,
-		},
-		25*time.Second,
-	)
-
-	streamName := id
-	stream.Start().Wait()
-	if !stream.Running {
-		if c.blacklist.AddOrIncrease(URI).IsBanned(URI) {
-			delete(c.preload, Alias)
+// recoverStream restarts a supervised stream whose process exited or stalled.
+// Failures count towards the blacklist, banned streams are not restarted anymore.
+func (c *Controller) recoverStream(stream *streamer.Stream) error {
+	if c.blacklist.IsBanned(stream.OriginalURI) {
+		return supervisor.ErrGiveUp
+	}
+	stream.Mux.Lock()
+	running := stream.Running
+	stream.Mux.Unlock()
+	if running {
+		// stalled processes are still running, they have to be stopped first
+		if err := stream.Stop(); err != nil {
+			logrus.Error(err)
 		}
-		return
 	}
+	// restarts are retried with backoff while the limits are reached, without counting towards the blacklist
+	restarted := false
+	err := c.startAdmitted(stream.OriginalURI, func() {
+		restarted = c.startStream(stream.OriginalURI, "", c.registry.Options(stream.ID)).Running
+	})
+	if err != nil {
+		return err
+	}
+	if restarted {
+		c.blacklist.Remove(stream.OriginalURI)
+		return nil
+	}
+	c.blacklist.AddOrIncrease(stream.OriginalURI)
+	c.metrics.StreamEvent(metrics.EventTimeout, stream.ID, c.registry.AliasOf(stream.ID))
+	return ErrTimeout
+}
 
-	c.streams[id] = stream
-	c.index[URI] = id
-	if len(Alias) > 0 {
-		c.alias[Alias] = id
-		streamName = Alias
+func (c *Controller) startPreloadStream(setting config.ListenSetting) {
+	logrus.Debugf("%s is being initialized", setting.Uri)
+
+	if c.blacklist.IsBanned(setting.Uri) {
+		return
+	}
+	// the camera is already known without the alias, e.g. it was started via /start
+	if stream, known := c.registry.GetByURI(setting.Uri); known {
+		c.registry.Add(stream, setting.Alias)
+		c.persist()
 	}
-	c.blacklist.Remove(URI)
-	delete(c.preload, Alias)
 
-	logrus.Infoln("started stream /stream/" + streamName + "/index.m3u8")
+	stream := c.startStream(setting.Uri, setting.Alias, setting.StreamOptions)
+	if !stream.Running {
+		return
+	}
+	logrus.Infoln("started stream /stream/" + setting.Alias + "/index.m3u8")
 }
 
 // StartStreamHandler is an HTTP handler for the POST /start endpoint
@@ -391,114 +681,180 @@ func (c *Controller) StartStreamHandler(w http.ResponseWriter, r *http.Request,
 		c.sendError(w, fmt.Errorf("%s cannot be started", dto.URI), http.StatusTooManyRequests)
 		return
 	}
-	index, knownStream := c.index[dto.URI]
-	if knownStream {
-		stream, ok := c.streams[index]
-		if !ok {
-			logrus.Errorf("Missing index for URI: %s | StartHandler", dto.URI)
-			c.sendError(w, ErrUnexpected, http.StatusInternalServerError)
+	if _, err := c.spec.Profile(dto.Profile); err != nil {
+		c.sendError(w, err, http.StatusBadRequest)
+		return
+	}
+	// the requested alias does not grant access, it could take over the alias of another stream
+	knownID := ""
+	if stream, ok := c.registry.GetByURI(dto.URI); ok {
+		knownID = stream.ID
+	}
+	claims := c.claims(r, "start")
+	if !c.canAccess(claims, knownID, c.registry.AliasOf(knownID), dto.URI) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if dto.Alias != "" {
+		// the token has to allow the alias itself, not only the camera it is requested for
+		if !c.canAccess(claims, "", dto.Alias, "") {
+			w.WriteHeader(http.StatusForbidden)
 			return
 		}
-
-		// look for an alias since its known
-		alias := ""
-		for key, item := range c.alias {
-			if item == stream.ID {
-				alias = key
-			}
+		if err := c.aliasConflict(dto.Alias, dto.URI); err != nil {
+			c.sendError(w, err, http.StatusConflict)
+			return
 		}
-
-		if stream.Running {
-			c.sendStart(w, true, stream, alias)
+	}
+	if stream, ok := c.registry.GetByURI(dto.URI); !ok || !stream.Running {
+		done, err := c.admit(r, "start", dto.URI)
+		if err != nil {
+			c.sendError(w, err, http.StatusServiceUnavailable)
 			return
 		}
-		stream.Restart().Wait()
-		c.sendStart(w, stream.Running, stream, alias)
-		return
+		defer done()
 	}
+	stream := c.startStream(dto.URI, dto.Alias, dto.StreamOptions)
+	c.sendStart(w, stream.Running, stream, c.registry.AliasOf(stream.ID))
+}
 
-	stream, id := streamer.NewStream(
-		dto.URI,
-		c.spec.StoreDir,
-		c.spec.KeepFiles,
-		c.spec.Audio,
-		streamer.ProcessLoggingOpts{
-			Enabled:    c.spec.ProcessLogging.Enabled,
-			Compress:   c.spec.ProcessLogging.Compress,
-			Directory:  c.spec.ProcessLogging.Directory,
-			MaxAge:     c.spec.ProcessLogging.MaxAge,
-			MaxBackups: c.spec.ProcessLogging.MaxBackups,
-			MaxSize:    c.spec.ProcessLogging.MaxSize,
-		},
-		25*time.Second,
-	)
-	stream.Start().Wait()
-	if stream.Running {
-		c.streams[id] = stream
-		c.index[dto.URI] = id
-		c.blacklist.Remove(dto.URI)
-		// only add an alias if provided
-		if len(dto.Alias) > 0 {
-			c.alias[dto.Alias] = id
+// aliasConflict returns an error if the alias is bound to another URI, by a stream or by a listen entry
+func (c *Controller) aliasConflict(alias string, uri string) error {
+	if id, ok := c.registry.ResolveAlias(alias); ok {
+		if stream, ok := c.registry.Get(id); ok && stream.OriginalURI != uri {
+			return fmt.Errorf("%s is used by another stream", alias)
 		}
-	} else {
-		c.blacklist.AddOrIncrease(dto.URI)
 	}
-	c.sendStart(w, stream.Running, stream, dto.Alias)
+	if setting, ok := c.registry.Preload(alias); ok && setting.Uri != uri {
+		return fmt.Errorf("%s is used by another listen entry", alias)
+	}
+	return nil
 }
 
 func (c *Controller) shouldRedirectAlias(alias string, filepath string) (string, bool) {
-	id, ok := c.alias[alias]
+	id, ok := c.registry.ResolveAlias(alias)
 	if !ok {
 		return "", false
 	}
 
-	url := strings.Join(
-		[]string{
-			"/stream",
-			id,
-			path.Base(filepath),
-		}, "/")
+	// keep everything after the alias, so files of renditions are redirected as well
+	parts := []string{"/stream", id}
+	if segments := strings.Split(filepath, "/"); len(segments) > 2 {
+		parts = append(parts, segments[2:]...)
+	}
+	url := strings.Join(parts, "/")
 
 	return url, true
 }
 
 // StaticFileHandler is HTTP handler for direct file requests
 func (c *Controller) StaticFileHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
-	if !c.isAuthenticated(req, "static") {
-		w.WriteHeader(http.StatusForbidden)
-		return
-	}
-	defer c.fileServer.ServeHTTP(w, req)
 	filepath := ps.ByName("filepath")
 	req.URL.Path = filepath
 	id := c.getIDByPath(filepath)
+	// signed URLs are used instead of tokens
+	signed, err := c.verifySignature(req, id)
+	if err != nil {
+		c.sendError(w, err, http.StatusForbidden)
+		return
+	}
+	if !signed && (!c.isAuthenticated(req, "static") || !c.canAccessRef(req, "static", id)) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	// files listed in playlists and redirects are authenticated the same way as the request
+	forward := url.Values{}
+	if signed {
+		forward = signature(req)
+	} else {
+		forward = c.queryToken(req, "static")
+	}
 
-	// start preload if registered
-	uri, ok := c.preload[id]
-	if ok {
+	// start preload if registered and not started yet, started ones are restarted via their stream
+	setting, ok := c.registry.Preload(id)
+	if _, started := c.registry.ResolveAlias(id); ok && !started {
+		done, err := c.admit(req, "static", setting.Uri)
+		if err != nil {
+			c.sendError(w, err, http.StatusServiceUnavailable)
+			return
+		}
+		defer done()
 		logrus.Infoln("starting preload " + id + " now")
-		c.startPreloadStream(id, uri)
+		c.events.Publish(events.Event{Type: events.Preloaded, Alias: setting.Alias, URI: setting.Uri})
+		c.startPreloadStream(setting)
 	}
 
 	// redirect alias if used
 
 	if url, ok := c.shouldRedirectAlias(id, filepath); ok {
+		// options like the DVR range are kept, credentials are added after logging
+		url = withQuery(url, withoutCredentials(req.URL.Query()))
 		logrus.Infoln("redirecting alias " + id + " to " + url)
+		if signed {
+			target, _ := c.registry.ResolveAlias(id)
+			url = withQuery(url, c.signer.Sign(target, auth.Expires(req.URL.Query())))
+		} else {
+			url = withQuery(url, forward)
+		}
 		http.Redirect(w, req, url, 302)
 		return
 	}
 
-	stream, ok := c.streams[id]
+	if rewrite, ok := manifestRewriters[path.Ext(filepath)]; ok && len(forward) > 0 {
+		req.Header.Del("If-Modified-Since")
+		req.Header.Del("Range")
+		rewriter := &playlistRewriter{ResponseWriter: w, query: forward, rewrite: rewrite}
+		defer rewriter.flush()
+		w = rewriter
+	}
+
+	stream, ok := c.registry.Get(id)
 	if !ok {
+		c.fileServer.ServeHTTP(w, req)
 		return
 	}
-	if stream.Streak.IsActive() || stream.Running {
+	if path.Ext(filepath) != ".m3u8" {
+		c.metrics.SegmentRequest(id)
+	}
+	c.viewers.Hit(id, c.viewerKey(req))
+	// supervised streams are being restarted in the background, viewers do not have to wait for it
+	if stream.Streak.IsActive() || stream.Running || c.supervisor.Watching(id) {
 		stream.Streak.Hit()
+	} else {
+		logrus.Debugf("%s is getting restarted via file requests | FileHandler", id)
+		done, err := c.admit(req, "static", stream.OriginalURI)
+		if err != nil {
+			c.sendError(w, err, http.StatusServiceUnavailable)
+			return
+		}
+		defer done()
+		c.startStream(stream.OriginalURI, "", c.registry.Options(id))
+	}
+
+	profile, err := c.spec.Profile(c.registry.Options(id).Profile)
+	if err == nil && path.Base(filepath) == DVRPlaylist {
+		c.serveDVR(w, req, profile)
+		return
+	}
+	if err == nil && profile.LowLatency {
+		c.serveLowLatency(w, req, profile)
+		return
+	}
+	c.fileServer.ServeHTTP(w, req)
+}
+
+// MetricsHandler is the HTTP handler of the GET /metrics call
+func (c *Controller) MetricsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !c.isAuthenticated(r, "metrics") {
+		w.WriteHeader(http.StatusForbidden)
 		return
 	}
-	logrus.Debugf("%s is getting restarted via file requests | FileHandler", id)
-	stream.Restart().Wait()
+	c.metrics.Handler().ServeHTTP(w, r)
+}
+
+// Instrument wraps the given handle to observe its latency for the metrics endpoint
+func (c *Controller) Instrument(route string, handle httprouter.Handle) httprouter.Handle {
+	return c.metrics.Instrument(route, handle)
 }
 
 // ExitPreHook is a function that can recognise when the application is being closed
@@ -509,8 +865,9 @@ func (c *Controller) ExitPreHook() chan bool {
 	signal.Notify(ch, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	go func() {
 		<-ch
-		for uri, strm := range c.streams {
+		for uri, strm := range c.registry.Streams() {
 			logrus.Debugf("Closing processing of %s", uri)
+			c.supervisor.Unwatch(uri)
 			if err := strm.Stop(); err != nil {
 				logrus.Error(err)
 				return
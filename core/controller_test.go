@@ -2,8 +2,33 @@ package core
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/Roverr/hotstreak"
+	"github.com/Roverr/rtsp-stream/core/auth"
+	"github.com/Roverr/rtsp-stream/core/blacklist"
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/events"
+	"github.com/Roverr/rtsp-stream/core/limits"
+	"github.com/Roverr/rtsp-stream/core/metrics"
+	"github.com/Roverr/rtsp-stream/core/recording"
+	"github.com/Roverr/rtsp-stream/core/registry"
+	"github.com/Roverr/rtsp-stream/core/snapshot"
+	"github.com/Roverr/rtsp-stream/core/store"
+	"github.com/Roverr/rtsp-stream/core/supervisor"
+	"github.com/Roverr/rtsp-stream/core/transcoder"
+	"github.com/Roverr/rtsp-stream/core/viewers"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/riltech/streamer"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,11 +56,9 @@ func TestGetIDByPath(t *testing.T) {
 }
 
 func TestRedirectAlias(t *testing.T) {
-	c := &Controller{
-		alias: map[string]string{
-			"alias": "id",
-		},
-	}
+	reg := registry.NewRegistry()
+	reg.Add(&streamer.Stream{ID: "id", OriginalURI: "rtsp://host/alias"}, "alias")
+	c := &Controller{registry: reg}
 
 	tt := []struct {
 		FilePath string
@@ -57,6 +80,18 @@ func TestRedirectAlias(t *testing.T) {
 			FilePath: "stream/alias/index.m3u8",
 			Expected: "/stream/id/index.m3u8",
 		},
+		{
+			FilePath: "/alias/master.m3u8",
+			Expected: "/stream/id/master.m3u8",
+		},
+		{
+			FilePath: "/alias/360p/index.m3u8",
+			Expected: "/stream/id/360p/index.m3u8",
+		},
+		{
+			FilePath: "/alias/360p/12.ts",
+			Expected: "/stream/id/360p/12.ts",
+		},
 	}
 
 	for i, testCase := range tt {
@@ -68,4 +103,245 @@ func TestRedirectAlias(t *testing.T) {
 			t.Error(fmt.Errorf("%d testcase is failing", i))
 		}
 	}
+
+	// the query of the request is kept, like the range of DVR playlists
+	c = newTestController()
+	c.registry = reg
+	w := httptest.NewRecorder()
+	file := "/alias/dvr.m3u8"
+	c.StaticFileHandler(w, httptest.NewRequest(http.MethodGet, "/stream"+file+"?start=2020-01-02T10:00:00Z&end=2020-01-02T10:05:00Z", nil), httprouter.Params{{Key: "filepath", Value: file}})
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/stream/id/dvr.m3u8?end=2020-01-02T10%3A05%3A00Z&start=2020-01-02T10%3A00%3A00Z", w.Header().Get("Location"))
+}
+
+func newRunningStream(id string, uri string) *streamer.Stream {
+	return &streamer.Stream{
+		ID:          id,
+		OriginalURI: uri,
+		Running:     true,
+		Mux:         &sync.Mutex{},
+		Streak: hotstreak.New(hotstreak.Config{
+			Limit:      10,
+			HotWait:    time.Minute * 2,
+			ActiveWait: time.Minute * 4,
+		}).Activate(),
+	}
+}
+
+func newTestController() *Controller {
+	return &Controller{
+		spec:            &config.Specification{},
+		endpoints:       &atomic.Value{},
+		registry:        registry.NewRegistry(),
+		blacklist:       (*blacklist.List)(nil),
+		metrics:         (*metrics.Metrics)(nil),
+		supervisor:      (*supervisor.Supervisor)(nil),
+		events:          (*events.Bus)(nil),
+		store:           (*store.FileStore)(nil),
+		janitor:         (*recording.Janitor)(nil),
+		snapshots:       snapshot.NewCache(time.Minute),
+		viewers:         viewers.NewTracker(time.Minute),
+		limiter:         (*limits.Limiter)(nil),
+		persistMu:       &sync.Mutex{},
+		listenMu:        &sync.Mutex{},
+		listen:          map[string]config.ListenSetting{},
+		fileServer:      http.NotFoundHandler(),
+		recordingServer: http.NotFoundHandler(),
+		policy:          newPolicy(),
+		signer:          (*auth.Signer)(nil),
+	}
+}
+
+func TestConcurrentHandlers(t *testing.T) {
+	c := newTestController()
+	for i := 0; i < 10; i++ {
+		c.registry.Add(newRunningStream(fmt.Sprintf("id%d", i), fmt.Sprintf("rtsp://host/%d", i)), fmt.Sprintf("cam%d", i))
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(5)
+		n := i % 10
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			c.ListStreamHandler(w, httptest.NewRequest("GET", "/list", nil), nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}()
+		go func() {
+			defer wg.Done()
+			body := strings.NewReader(fmt.Sprintf(`{"uri":"rtsp://host/%d"}`, n))
+			w := httptest.NewRecorder()
+			c.StartStreamHandler(w, httptest.NewRequest("POST", "/start", body), nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}()
+		go func() {
+			defer wg.Done()
+			file := fmt.Sprintf("/id%d/index.m3u8", n)
+			w := httptest.NewRecorder()
+			c.StaticFileHandler(w, httptest.NewRequest("GET", "/stream"+file, nil), httprouter.Params{{Key: "filepath", Value: file}})
+		}()
+		go func() {
+			defer wg.Done()
+			file := fmt.Sprintf("/cam%d/index.m3u8", n)
+			w := httptest.NewRecorder()
+			c.StaticFileHandler(w, httptest.NewRequest("GET", "/stream"+file, nil), httprouter.Params{{Key: "filepath", Value: file}})
+			assert.Equal(t, http.StatusFound, w.Code)
+		}()
+		go func() {
+			defer wg.Done()
+			c.registry.SetPreload(config.ListenSetting{Uri: fmt.Sprintf("rtsp://other/%d", n), Alias: fmt.Sprintf("preload%d", n)})
+			c.registry.RemovePreload(fmt.Sprintf("preload%d", n))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, len(c.registry.Streams()))
+}
+
+func TestConcurrentStop(t *testing.T) {
+	c := newTestController()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(n int) {
+			defer wg.Done()
+			c.registry.Add(newRunningStream(fmt.Sprintf("id%d", n), fmt.Sprintf("rtsp://host/%d", n)), "")
+		}(i)
+		go func(n int) {
+			defer wg.Done()
+			body := strings.NewReader(fmt.Sprintf(`{"id":"unknown%d","remove":true}`, n))
+			w := httptest.NewRecorder()
+			c.StopStreamHandler(w, httptest.NewRequest("POST", "/stop", body), nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 20, len(c.registry.Streams()))
+}
+
+func TestRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-restore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileStore, err := store.NewFileStore(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, fileStore.Save([]store.Record{
+		{ID: "id", URI: "rtsp://host/1", Alias: "camera"},
+		{ID: "id2", URI: "rtsp://host/2"},
+	}))
+
+	c := newTestController()
+	c.spec.StoreDir = dir
+	c.store = fileStore
+	c.restore()
+
+	stream, ok := c.registry.GetByURI("rtsp://host/1")
+	assert.True(t, ok)
+	assert.Equal(t, "id", stream.ID)
+	assert.False(t, stream.Running)
+	url, ok := c.shouldRedirectAlias("camera", "/camera/index.m3u8")
+	assert.True(t, ok)
+	assert.Equal(t, "/stream/id/index.m3u8", url)
+	stream, ok = c.registry.GetByURI("rtsp://host/2")
+	assert.True(t, ok)
+	assert.Equal(t, "id2", stream.ID)
+
+	c.persist()
+	records, err := fileStore.Load()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []store.Record{
+		{ID: "id", URI: "rtsp://host/1", Alias: "camera"},
+		{ID: "id2", URI: "rtsp://host/2"},
+	}, records)
+}
+
+func TestStartWithUnknownProfile(t *testing.T) {
+	c := newTestController()
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"uri":"rtsp://host/1","profile":"unknown"}`)
+	c.StartStreamHandler(w, httptest.NewRequest("POST", "/start", body), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, c.registry.Streams())
+}
+
+func TestSummariseURIs(t *testing.T) {
+	dash := true
+	c := newTestController()
+	c.spec.Profiles = map[string]config.Profile{
+		"dash":     {Dash: &dash},
+		"adaptive": {Renditions: []config.Rendition{{Name: "360p", Height: 360, Bitrate: "800k"}}, DVRWindow: time.Minute},
+	}
+	c.registry.SetOptions("id", config.StreamOptions{Profile: "dash"})
+	c.registry.Add(newRunningStream("id", "rtsp://host/1"), "camera")
+	c.registry.SetPreload(config.ListenSetting{Uri: "rtsp://host/2", Alias: "preload", StreamOptions: config.StreamOptions{Profile: "adaptive"}})
+
+	w := httptest.NewRecorder()
+	c.ListStreamHandler(w, httptest.NewRequest("GET", "/list", nil), nil)
+	body := w.Body.String()
+	assert.Contains(t, body, `"dash_uri":"/stream/camera/manifest.mpd"`)
+	assert.Contains(t, body, `"master_uri":"/stream/preload/master.m3u8"`)
+	assert.Equal(t, 1, strings.Count(body, "dash_uri"))
+	assert.Equal(t, 1, strings.Count(body, "master_uri"))
+	assert.Contains(t, body, `"dvr_uri":"/stream/preload/dvr.m3u8"`)
+	assert.Equal(t, 1, strings.Count(body, "dvr_uri"))
+}
+
+func TestRecoverStream(t *testing.T) {
+	c := newTestController()
+	c.blacklist = blacklist.NewList(time.Hour, 0)
+	stream := newRunningStream("id", "rtsp://host/1")
+	stream.Process = transcoder.NewProcess(config.Profile{}, transcoder.Recording{})
+	c.registry.Add(stream, "")
+	stream.Running = false
+
+	assert.Nil(t, c.recoverStream(stream))
+	assert.True(t, stream.Running)
+
+	c.blacklist.AddOrIncrease("rtsp://host/1").AddOrIncrease("rtsp://host/1")
+	assert.Equal(t, supervisor.ErrGiveUp, c.recoverStream(stream))
+}
+
+// newAuthController returns a test controller verifying tokens signed with "secret"
+func newAuthController(t *testing.T) *Controller {
+	c := newTestController()
+	c.spec.JWTEnabled = true
+	provider, err := auth.NewJWTProvider(config.Auth{JWTMethod: "secret", JWTSecret: "secret"})
+	assert.Nil(t, err)
+	c.jwt = provider
+	return c
+}
+
+// newTokenRequest returns a request carrying a token with the given claims signed with "secret"
+func newTokenRequest(t *testing.T, method string, target string, body string, claims jwt.MapClaims) *http.Request {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	assert.Nil(t, err)
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestIsAuthenticated(t *testing.T) {
+	c := newAuthController(t)
+	c.endpoints.Store(config.Endpoints{
+		Start:  config.EndpointSetting{Enabled: true, Secret: "macilaci"},
+		List:   config.EndpointSetting{Enabled: true, Scopes: []string{"admin"}},
+		Static: config.EndpointSetting{Enabled: true},
+	})
+	request := func(claims jwt.MapClaims) *http.Request {
+		return newTokenRequest(t, "GET", "/list", "", claims)
+	}
+
+	assert.False(t, c.isAuthenticated(httptest.NewRequest("GET", "/list", nil), "static"))
+	assert.True(t, c.isAuthenticated(request(jwt.MapClaims{}), "static"))
+	assert.False(t, c.isAuthenticated(request(jwt.MapClaims{}), "start"))
+	assert.True(t, c.isAuthenticated(request(jwt.MapClaims{"secret": "macilaci"}), "start"))
+	assert.False(t, c.isAuthenticated(request(jwt.MapClaims{"secret": "macilaci"}), "list"))
+
+	viewer := request(jwt.MapClaims{"scopes": "stream:view"})
+	assert.True(t, c.isAuthenticated(viewer, "static"))
+	assert.False(t, c.isAuthenticated(viewer, "start"))
+	assert.True(t, c.isAuthenticated(request(jwt.MapClaims{"scopes": []string{"stream:start"}}), "start"))
+	assert.True(t, c.isAuthenticated(request(jwt.MapClaims{"scopes": "admin"}), "list"))
+	assert.False(t, c.isAuthenticated(request(jwt.MapClaims{"scopes": "stream:list"}), "list"))
+	assert.False(t, c.isAuthenticated(request(jwt.MapClaims{"scopes": "stream:view", "exp": time.Now().Add(-time.Minute).Unix()}), "static"))
 }
@@ -0,0 +1,20 @@
+package dash
+
+import (
+	"html"
+	"regexp"
+)
+
+// uriAttribute matches the attributes of segment templates and segment lists holding URIs
+var uriAttribute = regexp.MustCompile(`(\s)(initialization|media|sourceURL)="([^"]*)"`)
+
+// RewriteURIs returns the MPEG-DASH manifest with the URI of every segment replaced by the result of rewrite,
+// including the segment templates written by ffmpeg. BaseURL elements are kept, as their query is not
+// applied to the segments resolved against them. Values are unescaped before and escaped after rewriting.
+func RewriteURIs(data []byte, rewrite func(uri string) string) []byte {
+	return uriAttribute.ReplaceAllFunc(data, func(attribute []byte) []byte {
+		match := uriAttribute.FindSubmatch(attribute)
+		uri := html.EscapeString(rewrite(html.UnescapeString(string(match[3]))))
+		return []byte(string(match[1]) + string(match[2]) + `="` + uri + `"`)
+	})
+}
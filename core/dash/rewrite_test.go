@@ -0,0 +1,37 @@
+package dash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteURIs(t *testing.T) {
+	rewrite := func(uri string) string { return uri + "?expires=1&signature=abc" }
+	manifest := RewriteURIs([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="dynamic">
+	<Period id="0" start="PT0.0S">
+		<AdaptationSet id="0" contentType="video" mimeType="video/mp4">
+			<Representation id="0" codecs="avc1.64001f" bandwidth="800000">
+				<SegmentTemplate timescale="90000" initialization="init-$RepresentationID$.m4s" media="chunk-$RepresentationID$-$Number%05d$.m4s" startNumber="1">
+				</SegmentTemplate>
+			</Representation>
+		</AdaptationSet>
+	</Period>
+	<BaseURL>segments/</BaseURL>
+</MPD>
+`), rewrite)
+	assert.Equal(t, `<?xml version="1.0" encoding="utf-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="dynamic">
+	<Period id="0" start="PT0.0S">
+		<AdaptationSet id="0" contentType="video" mimeType="video/mp4">
+			<Representation id="0" codecs="avc1.64001f" bandwidth="800000">
+				<SegmentTemplate timescale="90000" initialization="init-$RepresentationID$.m4s?expires=1&amp;signature=abc" media="chunk-$RepresentationID$-$Number%05d$.m4s?expires=1&amp;signature=abc" startNumber="1">
+				</SegmentTemplate>
+			</Representation>
+		</AdaptationSet>
+	</Period>
+	<BaseURL>segments/</BaseURL>
+</MPD>
+`, string(manifest))
+}
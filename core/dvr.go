@@ -0,0 +1,73 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/hls"
+)
+
+// DVRPlaylist is the name of the timeshift playlist generated next to every playlist of a stream
+const DVRPlaylist = "dvr.m3u8"
+
+// serveDVR serves the timeshift playlist of the segments kept for the DVR window.
+// The start and end query parameters select the range to play.
+func (c *Controller) serveDVR(w http.ResponseWriter, req *http.Request, profile config.Profile) {
+	if profile.DVRWindow <= 0 {
+		http.NotFound(w, req)
+		return
+	}
+	now := time.Now()
+	query := req.URL.Query()
+	start, err := parseDVRTime(query.Get("start"), now)
+	if err != nil {
+		c.sendError(w, err, http.StatusBadRequest)
+		return
+	}
+	end, err := parseDVRTime(query.Get("end"), now)
+	if err != nil {
+		c.sendError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	name := path.Clean("/" + req.URL.Path)
+	file := filepath.Join(c.spec.StoreDir, filepath.FromSlash(path.Dir(name)), "index.m3u8")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	playlist, err := hls.Parse(data)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	if profile.LowLatency {
+		ll := hls.LowLatency{PartTarget: profile.PartLength, PartsPerSegment: profile.PartsPerSegment()}
+		playlist = ll.Full(playlist)
+	}
+	window := hls.Window(playlist, start, end)
+	if len(window.Segments) == 0 {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(window.Encode())
+}
+
+// parseDVRTime reads a point of time either in RFC3339 format
+// or as a duration relative to now, like "-10m". Empty value returns zero time.
+func parseDVRTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
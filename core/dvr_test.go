@@ -0,0 +1,60 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeDVR(t *testing.T) {
+	storeDir, err := ioutil.TempDir("", "dvr")
+	assert.Nil(t, err)
+	defer os.RemoveAll(storeDir)
+	dir := filepath.Join(storeDir, "id")
+	assert.Nil(t, os.MkdirAll(dir, os.ModePerm))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "index.m3u8"), []byte(`#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:2
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PROGRAM-DATE-TIME:2020-01-02T10:00:00.000+0000
+#EXTINF:2.000000,
+0.ts
+#EXT-X-PROGRAM-DATE-TIME:2020-01-02T10:00:02.000+0000
+#EXTINF:2.000000,
+1.ts
+#EXT-X-PROGRAM-DATE-TIME:2020-01-02T10:00:04.000+0000
+#EXTINF:2.000000,
+2.ts
+`), 0644))
+
+	c := newTestController()
+	c.spec.StoreDir = storeDir
+	profile := config.Profile{SegmentLength: 2 * time.Second, DVRWindow: time.Minute}
+	serve := func(uri string, profile config.Profile) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c.serveDVR(w, httptest.NewRequest(http.MethodGet, uri, nil), profile)
+		return w
+	}
+
+	w := serve("/id/dvr.m3u8", profile)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, strings.Count(w.Body.String(), "#EXTINF"))
+	assert.NotContains(t, w.Body.String(), "#EXT-X-ENDLIST")
+
+	w = serve("/id/dvr.m3u8?start=2020-01-02T10:00:02Z&end=2020-01-02T10:00:04Z", profile)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "#EXT-X-MEDIA-SEQUENCE:1\n")
+	assert.Contains(t, w.Body.String(), "1.ts\n#EXT-X-ENDLIST")
+
+	assert.Equal(t, http.StatusBadRequest, serve("/id/dvr.m3u8?start=yesterday", profile).Code)
+	assert.Equal(t, http.StatusNotFound, serve("/id/dvr.m3u8?start=-1m", profile).Code)
+	assert.Equal(t, http.StatusNotFound, serve("/id/dvr.m3u8", config.Profile{}).Code)
+}
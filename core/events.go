@@ -0,0 +1,79 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/events"
+	"github.com/julienschmidt/httprouter"
+	"github.com/riltech/streamer"
+)
+
+// keepAliveInterval is the time between comments sent to idle event stream clients,
+// so proxies do not close the connection
+const keepAliveInterval = 15 * time.Second
+
+// banned publishes the event of an URI getting banned by the blacklist
+func (c *Controller) banned(uri string) {
+	event := events.Event{Type: events.Blacklisted, URI: uri}
+	if stream, ok := c.registry.GetByURI(uri); ok {
+		event.ID = stream.ID
+		event.Alias = c.registry.AliasOf(stream.ID)
+	}
+	c.events.Publish(event)
+}
+
+// failed publishes the event of a supervised stream exiting or stalling unexpectedly
+func (c *Controller) failed(stream *streamer.Stream, reason string) {
+	c.events.Publish(events.Event{
+		Type:   events.Failed,
+		ID:     stream.ID,
+		Alias:  c.registry.AliasOf(stream.ID),
+		URI:    stream.OriginalURI,
+		Reason: reason,
+	})
+}
+
+// EventsHandler is the HTTP handler of the GET /events call.
+// Stream lifecycle events are pushed to the client as Server-Sent Events until it disconnects.
+func (c *Controller) EventsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !c.isAuthenticated(r, "events") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		c.sendError(w, ErrUnexpected, http.StatusInternalServerError)
+		return
+	}
+	claims := c.claims(r, "events")
+	ch, cancel := c.events.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-ch:
+			// events are sent only for the streams the caller may access, patterns may include the credentials
+			if !c.canAccess(claims, event.ID, event.Alias, event.OriginalURI()) {
+				continue
+			}
+			b, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, b)
+		case <-time.After(keepAliveInterval):
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,116 @@
+package events
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Types of stream lifecycle events
+const (
+	Started     = "started"
+	Stopped     = "stopped"
+	Restarted   = "restarted"
+	Timeout     = "timeout"
+	Failed      = "failed"
+	Blacklisted = "blacklisted"
+	Aliased     = "alias_assigned"
+	Preloaded   = "preload_triggered"
+)
+
+// bufferSize is the number of events kept for a subscriber not reading them yet.
+// Events are dropped for subscribers falling further behind, so they cannot block the application.
+const bufferSize = 64
+
+// Event describes something that happened to a stream
+type Event struct {
+	Seq    uint64    `json:"seq"`
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	ID     string    `json:"id,omitempty"`
+	Alias  string    `json:"alias,omitempty"`
+	URI    string    `json:"uri"`
+	Reason string    `json:"reason,omitempty"`
+
+	original string // URI with the credentials of the camera, never sent out
+}
+
+// OriginalURI returns the URI of the stream as it was published, with the credentials of the camera
+func (e Event) OriginalURI() string {
+	return e.original
+}
+
+// IBus describes the user panel of the event bus
+type IBus interface {
+	Publish(event Event)
+	Subscribe() (<-chan Event, func())
+}
+
+// Bus implements IBus by fanning out events to every subscriber
+type Bus struct {
+	mu          *sync.Mutex
+	seq         uint64
+	subscribers map[chan Event]struct{}
+}
+
+// Type check
+var _ IBus = (*Bus)(nil)
+
+// NewBus creates a new Bus instance
+func NewBus() *Bus {
+	return &Bus{
+		mu:          &sync.Mutex{},
+		subscribers: map[chan Event]struct{}{},
+	}
+}
+
+// Publish numbers the event and sends it to every subscriber
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	event.original, event.URI = event.URI, redact(event.URI)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	event.Seq = b.seq
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every event published from now on
+// and a function to call once the subscriber is not interested anymore
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, bufferSize)
+	if b == nil {
+		return ch, func() {}
+	}
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+	once := &sync.Once{}
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// redact removes the credentials of the camera from the URI, as events leave the application
+func redact(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.User == nil {
+		return uri
+	}
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/blacklist"
+	"github.com/Roverr/rtsp-stream/core/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventsHandler(t *testing.T) {
+	c := newTestController()
+	c.events = events.NewBus()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.EventsHandler(w, r, nil)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	c.events.Publish(events.Event{Type: events.Started, ID: "id", Alias: "camera", URI: "rtsp://host/1"})
+	reader := bufio.NewReader(resp.Body)
+	lines := []string{}
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		assert.Nil(t, err)
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	assert.Equal(t, "id: 1", lines[0])
+	assert.Equal(t, "event: started", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "data: {"))
+	assert.Contains(t, lines[2], `"alias":"camera"`)
+}
+
+func TestBannedEvent(t *testing.T) {
+	c := newTestController()
+	c.events = events.NewBus()
+	c.blacklist = blacklist.NewList(time.Hour, 0).OnBan(c.banned)
+	c.registry.Add(newRunningStream("id", "rtsp://host/1"), "camera")
+	ch, cancel := c.events.Subscribe()
+	defer cancel()
+
+	c.blacklist.AddOrIncrease("rtsp://host/1").AddOrIncrease("rtsp://host/1")
+	event := <-ch
+	assert.Equal(t, events.Blacklisted, event.Type)
+	assert.Equal(t, "id", event.ID)
+	assert.Equal(t, "camera", event.Alias)
+}
@@ -0,0 +1,49 @@
+package hls
+
+import (
+	"time"
+)
+
+// Window returns the part of the playlist playing between start and end.
+// Segments overlapping the range are kept, zero start or end leaves the range open.
+// The playlist is ended if it cannot get any more segments in the range.
+func Window(p *Playlist, start time.Time, end time.Time) *Playlist {
+	window := &Playlist{
+		Version:       p.Version,
+		MediaSequence: p.MediaSequence,
+		Map:           p.Map,
+		Ended:         p.Ended,
+	}
+	var date time.Time
+	for i, segment := range p.Segments {
+		// dates are only written when they are not continuous
+		if !segment.ProgramDateTime.IsZero() {
+			date = segment.ProgramDateTime
+		}
+		var segmentEnd time.Time
+		if !date.IsZero() {
+			segmentEnd = date.Add(time.Duration(segment.Duration * float64(time.Second)))
+		}
+		if !start.IsZero() && (date.IsZero() || !segmentEnd.After(start)) {
+			window.MediaSequence = p.MediaSequence + i + 1
+			date = segmentEnd
+			continue
+		}
+		if !end.IsZero() && !date.IsZero() && !date.Before(end) {
+			window.Ended = true
+			break
+		}
+		segment.ProgramDateTime = date
+		window.Segments = append(window.Segments, segment)
+		if !end.IsZero() && !date.IsZero() && !segmentEnd.Before(end) {
+			window.Ended = true
+			break
+		}
+		date = segmentEnd
+	}
+	window.TargetDuration = window.MaxDuration()
+	if window.TargetDuration < p.TargetDuration {
+		window.TargetDuration = p.TargetDuration
+	}
+	return window
+}
@@ -0,0 +1,54 @@
+package hls
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow(t *testing.T) {
+	first := time.Date(2020, 1, 2, 10, 0, 0, 0, time.UTC)
+	playlist := &Playlist{Version: 3, TargetDuration: 2, MediaSequence: 10}
+	for i := 0; i < 5; i++ {
+		segment := Segment{Duration: 2, URI: fmt.Sprintf("%d.ts", 10+i)}
+		// ffmpeg only writes the first date after a discontinuity
+		if i == 0 || i == 3 {
+			segment.ProgramDateTime = first.Add(time.Duration(2*i) * time.Second)
+		}
+		playlist.Segments = append(playlist.Segments, segment)
+	}
+	uris := func(p *Playlist) []string {
+		list := []string{}
+		for _, segment := range p.Segments {
+			list = append(list, segment.URI)
+		}
+		return list
+	}
+
+	window := Window(playlist, time.Time{}, time.Time{})
+	assert.Equal(t, uris(playlist), uris(window))
+	assert.Equal(t, 10, window.MediaSequence)
+	assert.False(t, window.Ended)
+	assert.Equal(t, first.Add(2*time.Second), window.Segments[1].ProgramDateTime)
+
+	window = Window(playlist, first.Add(3*time.Second), time.Time{})
+	assert.Equal(t, []string{"11.ts", "12.ts", "13.ts", "14.ts"}, uris(window))
+	assert.Equal(t, 11, window.MediaSequence)
+	assert.False(t, window.Ended)
+
+	window = Window(playlist, first.Add(2*time.Second), first.Add(6*time.Second))
+	assert.Equal(t, []string{"11.ts", "12.ts"}, uris(window))
+	assert.Equal(t, 11, window.MediaSequence)
+	assert.True(t, window.Ended)
+	assert.Equal(t, 2, window.TargetDuration)
+
+	// the end is not reached yet, the client has to reload
+	window = Window(playlist, first.Add(8*time.Second), first.Add(time.Minute))
+	assert.Equal(t, []string{"14.ts"}, uris(window))
+	assert.False(t, window.Ended)
+
+	window = Window(playlist, first.Add(time.Minute), time.Time{})
+	assert.Empty(t, window.Segments)
+}
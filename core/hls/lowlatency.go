@@ -0,0 +1,145 @@
+package hls
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+)
+
+// PartFormat is the name format of the partial segments written by ffmpeg in low latency mode
+const PartFormat = "part%d.m4s"
+
+// SegmentFormat is the name format of full segments assembled from partial segments
+const SegmentFormat = "segment%d.m4s"
+
+// partWindow is the number of completed segments which still have their partial segments listed
+const partWindow = 2
+
+// LowLatency renders LL-HLS playlists out of playlists listing every partial segment as a segment.
+// Every PartsPerSegment consecutive partial segments form a full segment, whose media sequence number
+// is the sequence number of its first partial segment divided by PartsPerSegment.
+type LowLatency struct {
+	PartTarget      time.Duration // Target duration of partial segments
+	PartsPerSegment int           // Number of partial segments making up a full segment
+	Independent     bool          // Set if every partial segment starts on a keyframe
+}
+
+// lastPart returns the sequence number of the newest partial segment of the playlist
+func (ll LowLatency) lastPart(p *Playlist) int {
+	return p.MediaSequence + len(p.Segments) - 1
+}
+
+// Current returns the media sequence number of the full segment being built
+func (ll LowLatency) Current(p *Playlist) int {
+	return (ll.lastPart(p) + 1) / ll.PartsPerSegment
+}
+
+// Ready shows if the playlist already lists the given partial segment of a full segment.
+// Negative part means the full segment has to be completed.
+func (ll LowLatency) Ready(p *Playlist, msn int, part int) bool {
+	if part < 0 {
+		part = ll.PartsPerSegment - 1
+	}
+	return msn*ll.PartsPerSegment+part <= ll.lastPart(p)
+}
+
+// Parts returns the URIs of the partial segments building up the given full segment.
+// False is returned if the segment is not completed yet or it is not listed anymore.
+func (ll LowLatency) Parts(p *Playlist, msn int) ([]string, bool) {
+	first := msn*ll.PartsPerSegment - p.MediaSequence
+	if msn < 0 || first < 0 || first+ll.PartsPerSegment > len(p.Segments) {
+		return nil, false
+	}
+	uris := []string{}
+	for _, segment := range p.Segments[first : first+ll.PartsPerSegment] {
+		uris = append(uris, segment.URI)
+	}
+	return uris, true
+}
+
+// Full returns the playlist of the completed full segments, without partial segments
+func (ll LowLatency) Full(p *Playlist) *Playlist {
+	k := ll.PartsPerSegment
+	full := &Playlist{
+		Version:       p.Version,
+		MediaSequence: (p.MediaSequence + k - 1) / k,
+		Map:           p.Map,
+		Ended:         p.Ended,
+	}
+	for msn := full.MediaSequence; msn < ll.Current(p); msn++ {
+		start := msn*k - p.MediaSequence
+		segment := Segment{
+			URI:             fmt.Sprintf(SegmentFormat, msn),
+			ProgramDateTime: p.Segments[start].ProgramDateTime,
+		}
+		for _, part := range p.Segments[start : start+k] {
+			segment.Duration += part.Duration
+		}
+		full.Segments = append(full.Segments, segment)
+	}
+	full.TargetDuration = full.MaxDuration()
+	return full
+}
+
+// TargetDuration returns the expected duration of a full segment
+func (ll LowLatency) TargetDuration() time.Duration {
+	return ll.PartTarget * time.Duration(ll.PartsPerSegment)
+}
+
+// Render writes the LL-HLS playlist
+func (ll LowLatency) Render(p *Playlist) []byte {
+	k := ll.PartsPerSegment
+	partTarget := ll.PartTarget.Seconds()
+	for _, segment := range p.Segments {
+		partTarget = math.Max(partTarget, segment.Duration)
+	}
+	independent := ""
+	if ll.Independent {
+		independent = ",INDEPENDENT=YES"
+	}
+	first := (p.MediaSequence + k - 1) / k
+	current := ll.Current(p)
+
+	var body bytes.Buffer
+	targetDuration := int(math.Ceil(ll.TargetDuration().Seconds()))
+	for msn := first; msn <= current; msn++ {
+		start := msn*k - p.MediaSequence
+		end := start + k
+		if end > len(p.Segments) {
+			end = len(p.Segments)
+		}
+		if start >= end {
+			break
+		}
+		if date := p.Segments[start].ProgramDateTime; !date.IsZero() {
+			fmt.Fprintf(&body, "#EXT-X-PROGRAM-DATE-TIME:%s\n", date.Format(dateFormat))
+		}
+		duration := 0.0
+		for _, part := range p.Segments[start:end] {
+			duration += part.Duration
+			if msn >= current-partWindow {
+				fmt.Fprintf(&body, "#EXT-X-PART:DURATION=%s,URI=\"%s\"%s\n", decimal(part.Duration), part.URI, independent)
+			}
+		}
+		if msn < current {
+			fmt.Fprintf(&body, "#EXTINF:%s,\n%s\n", decimal(duration), fmt.Sprintf(SegmentFormat, msn))
+			if d := int(math.Ceil(duration)); d > targetDuration {
+				targetDuration = d
+			}
+		}
+	}
+	fmt.Fprintf(&body, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"%s\"\n", fmt.Sprintf(PartFormat, ll.lastPart(p)+1))
+
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:6\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", targetDuration)
+	fmt.Fprintf(&b, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%s\n", decimal(3*partTarget))
+	fmt.Fprintf(&b, "#EXT-X-PART-INF:PART-TARGET=%s\n", decimal(partTarget))
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", first)
+	if p.Map != "" {
+		fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"%s\"\n", p.Map)
+	}
+	b.Write(body.Bytes())
+	return b.Bytes()
+}
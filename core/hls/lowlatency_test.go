@@ -0,0 +1,77 @@
+package hls
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// partPlaylist creates a playlist written by ffmpeg in low latency mode
+func partPlaylist(first int, count int) *Playlist {
+	playlist := &Playlist{MediaSequence: first, Map: "init.mp4"}
+	for i := first; i < first+count; i++ {
+		playlist.Segments = append(playlist.Segments, Segment{Duration: 0.5, URI: fmt.Sprintf(PartFormat, i)})
+	}
+	return playlist
+}
+
+func TestLowLatencyRender(t *testing.T) {
+	ll := LowLatency{PartTarget: 500 * time.Millisecond, PartsPerSegment: 4, Independent: true}
+	// parts 9 - 18: segment 2 is missing part 8, segments 3 and 4 are complete, segment 4 is in progress
+	playlist := partPlaylist(9, 10)
+	assert.Equal(t, 4, ll.Current(playlist))
+	assert.Equal(t, `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:2
+#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=1.5
+#EXT-X-PART-INF:PART-TARGET=0.5
+#EXT-X-MEDIA-SEQUENCE:3
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-PART:DURATION=0.5,URI="part12.m4s",INDEPENDENT=YES
+#EXT-X-PART:DURATION=0.5,URI="part13.m4s",INDEPENDENT=YES
+#EXT-X-PART:DURATION=0.5,URI="part14.m4s",INDEPENDENT=YES
+#EXT-X-PART:DURATION=0.5,URI="part15.m4s",INDEPENDENT=YES
+#EXTINF:2,
+segment3.m4s
+#EXT-X-PART:DURATION=0.5,URI="part16.m4s",INDEPENDENT=YES
+#EXT-X-PART:DURATION=0.5,URI="part17.m4s",INDEPENDENT=YES
+#EXT-X-PART:DURATION=0.5,URI="part18.m4s",INDEPENDENT=YES
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="part19.m4s"
+`, string(ll.Render(playlist)))
+
+	// parts are not marked independent unless they start on a keyframe
+	ll.Independent = false
+	assert.NotContains(t, string(ll.Render(playlist)), "INDEPENDENT")
+}
+
+func TestLowLatencyReady(t *testing.T) {
+	ll := LowLatency{PartTarget: 500 * time.Millisecond, PartsPerSegment: 4}
+	playlist := partPlaylist(9, 10)
+	assert.True(t, ll.Ready(playlist, 3, -1))
+	assert.True(t, ll.Ready(playlist, 4, 2))
+	assert.False(t, ll.Ready(playlist, 4, 3))
+	assert.False(t, ll.Ready(playlist, 4, -1))
+	assert.False(t, ll.Ready(playlist, 5, 0))
+}
+
+func TestLowLatencyParts(t *testing.T) {
+	ll := LowLatency{PartTarget: 500 * time.Millisecond, PartsPerSegment: 4}
+	playlist := partPlaylist(9, 10)
+	parts, ok := ll.Parts(playlist, 3)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"part12.m4s", "part13.m4s", "part14.m4s", "part15.m4s"}, parts)
+	_, ok = ll.Parts(playlist, 2)
+	assert.False(t, ok)
+	_, ok = ll.Parts(playlist, 4)
+	assert.False(t, ok)
+}
+
+func TestLowLatencyFull(t *testing.T) {
+	ll := LowLatency{PartTarget: 500 * time.Millisecond, PartsPerSegment: 4}
+	full := ll.Full(partPlaylist(9, 10))
+	assert.Equal(t, 3, full.MediaSequence)
+	assert.Equal(t, 2, full.TargetDuration)
+	assert.Equal(t, []Segment{{Duration: 2, URI: "segment3.m4s"}}, full.Segments)
+}
@@ -0,0 +1,140 @@
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPlaylist describes an error related to reading a playlist
+var ErrInvalidPlaylist = errors.New("Invalid playlist")
+
+// Segment describes a media segment of a playlist
+type Segment struct {
+	Duration        float64
+	URI             string
+	ProgramDateTime time.Time // Zero if the playlist does not carry dates
+}
+
+// Playlist describes a media playlist
+type Playlist struct {
+	Version        int
+	TargetDuration int
+	MediaSequence  int
+	Map            string // URI of the initialization section, empty for MPEG-TS segments
+	Segments       []Segment
+	Ended          bool
+}
+
+// Parse reads a media playlist. Tags which are not used by the application are skipped.
+func Parse(data []byte) (*Playlist, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "#EXTM3U" {
+		return nil, ErrInvalidPlaylist
+	}
+	playlist := &Playlist{}
+	segment := Segment{}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		var err error
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXT-X-VERSION:"):
+			playlist.Version, err = strconv.Atoi(value(line))
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			playlist.TargetDuration, err = strconv.Atoi(value(line))
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			playlist.MediaSequence, err = strconv.Atoi(value(line))
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			playlist.Map = attribute(value(line), "URI")
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			segment.ProgramDateTime, err = parseDate(value(line))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			segment.Duration, err = strconv.ParseFloat(strings.Split(value(line), ",")[0], 64)
+		case line == "#EXT-X-ENDLIST":
+			playlist.Ended = true
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			segment.URI = line
+			playlist.Segments = append(playlist.Segments, segment)
+			segment = Segment{}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", ErrInvalidPlaylist, line)
+		}
+	}
+	return playlist, scanner.Err()
+}
+
+// Encode writes the playlist in the m3u8 format
+func (p *Playlist) Encode() []byte {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	if p.Version > 0 {
+		fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", p.Version)
+	}
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", p.TargetDuration)
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", p.MediaSequence)
+	if p.Map != "" {
+		fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"%s\"\n", p.Map)
+	}
+	for _, segment := range p.Segments {
+		if !segment.ProgramDateTime.IsZero() {
+			fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", segment.ProgramDateTime.Format(dateFormat))
+		}
+		fmt.Fprintf(&b, "#EXTINF:%s,\n%s\n", decimal(segment.Duration), segment.URI)
+	}
+	if p.Ended {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return b.Bytes()
+}
+
+// MaxDuration returns the duration of the longest segment rounded up to seconds
+func (p *Playlist) MaxDuration() int {
+	longest := 0.0
+	for _, segment := range p.Segments {
+		longest = math.Max(longest, segment.Duration)
+	}
+	return int(math.Ceil(longest))
+}
+
+// dateFormat is the format of written EXT-X-PROGRAM-DATE-TIME values
+const dateFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// parseDate reads EXT-X-PROGRAM-DATE-TIME values. ffmpeg writes the zone offset without colon.
+func parseDate(date string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02T15:04:05.999999999Z0700", date)
+	if err != nil {
+		return time.Parse(time.RFC3339Nano, date)
+	}
+	return t, nil
+}
+
+// value returns everything after the colon of a tag
+func value(line string) string {
+	return line[strings.Index(line, ":")+1:]
+}
+
+// attribute returns the value of the given attribute from an attribute list
+func attribute(list string, name string) string {
+	for _, item := range strings.Split(list, ",") {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == name {
+			return strings.Trim(parts[1], `"`)
+		}
+	}
+	return ""
+}
+
+// decimal formats seconds the way playlists carry them
+func decimal(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', -1, 64)
+}
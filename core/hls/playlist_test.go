@@ -0,0 +1,78 @@
+package hls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	playlist, err := Parse([]byte(`#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:2
+#EXT-X-MEDIA-SEQUENCE:12
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-PROGRAM-DATE-TIME:2020-01-02T10:00:00.500+0000
+#EXTINF:2.000000,
+12.ts
+#EXT-X-PROGRAM-DATE-TIME:2020-01-02T10:00:02.500+0000
+#EXTINF:1.960000,
+13.ts
+#EXT-X-ENDLIST
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, 7, playlist.Version)
+	assert.Equal(t, 2, playlist.TargetDuration)
+	assert.Equal(t, 12, playlist.MediaSequence)
+	assert.Equal(t, "init.mp4", playlist.Map)
+	assert.True(t, playlist.Ended)
+	assert.Equal(t, []Segment{
+		{Duration: 2, URI: "12.ts", ProgramDateTime: time.Date(2020, 1, 2, 10, 0, 0, 500000000, time.UTC)},
+		{Duration: 1.96, URI: "13.ts", ProgramDateTime: time.Date(2020, 1, 2, 10, 0, 2, 500000000, time.UTC)},
+	}, fixZones(playlist.Segments))
+	assert.Equal(t, 2, playlist.MaxDuration())
+
+	_, err = Parse([]byte("not a playlist"))
+	assert.Equal(t, ErrInvalidPlaylist, err)
+	_, err = Parse([]byte("#EXTM3U\n#EXTINF:abc,\n1.ts\n"))
+	assert.NotNil(t, err)
+}
+
+func TestEncode(t *testing.T) {
+	playlist := &Playlist{
+		Version:        3,
+		TargetDuration: 2,
+		MediaSequence:  5,
+		Segments: []Segment{
+			{Duration: 2, URI: "5.ts", ProgramDateTime: time.Date(2020, 1, 2, 10, 0, 0, 0, time.UTC)},
+			{Duration: 1.5, URI: "6.ts"},
+		},
+		Ended: true,
+	}
+	assert.Equal(t, `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:2
+#EXT-X-MEDIA-SEQUENCE:5
+#EXT-X-PROGRAM-DATE-TIME:2020-01-02T10:00:00.000Z
+#EXTINF:2,
+5.ts
+#EXTINF:1.5,
+6.ts
+#EXT-X-ENDLIST
+`, string(playlist.Encode()))
+
+	parsed, err := Parse(playlist.Encode())
+	assert.Nil(t, err)
+	assert.Equal(t, playlist.Segments, fixZones(parsed.Segments))
+}
+
+// fixZones converts dates into UTC, so they can be compared with assert.Equal
+func fixZones(segments []Segment) []Segment {
+	for i := range segments {
+		if !segments[i].ProgramDateTime.IsZero() {
+			segments[i].ProgramDateTime = segments[i].ProgramDateTime.UTC()
+		}
+	}
+	return segments
+}
@@ -0,0 +1,29 @@
+package hls
+
+import (
+	"regexp"
+	"strings"
+)
+
+// uriAttribute matches the URI attribute of tags, like in EXT-X-MAP or EXT-X-PART
+var uriAttribute = regexp.MustCompile(`([:,])URI="([^"]*)"`)
+
+// RewriteURIs returns the playlist with every URI replaced by the result of rewrite,
+// including the URI attributes of tags. Works with both media and master playlists.
+func RewriteURIs(data []byte, rewrite func(uri string) string) []byte {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			lines[i] = uriAttribute.ReplaceAllStringFunc(line, func(attribute string) string {
+				match := uriAttribute.FindStringSubmatch(attribute)
+				return match[1] + `URI="` + rewrite(match[2]) + `"`
+			})
+		default:
+			lines[i] = rewrite(trimmed)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
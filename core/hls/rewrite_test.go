@@ -0,0 +1,30 @@
+package hls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteURIs(t *testing.T) {
+	rewrite := func(uri string) string { return uri + "?signed" }
+	media := RewriteURIs([]byte(`#EXTM3U
+#EXT-X-TARGETDURATION:2
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-PART:DURATION=0.5,URI="part12.m4s",INDEPENDENT=YES
+#EXTINF:2.000000,
+12.m4s
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="part13.m4s"
+`), rewrite)
+	assert.Equal(t, `#EXTM3U
+#EXT-X-TARGETDURATION:2
+#EXT-X-MAP:URI="init.mp4?signed"
+#EXT-X-PART:DURATION=0.5,URI="part12.m4s?signed",INDEPENDENT=YES
+#EXTINF:2.000000,
+12.m4s?signed
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="part13.m4s?signed"
+`, string(media))
+
+	master := RewriteURIs([]byte("#EXTM3U\r\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",URI=\"audio/index.m3u8\"\r\n#EXT-X-STREAM-INF:BANDWIDTH=800000\r\n360p/index.m3u8\r\n"), rewrite)
+	assert.Equal(t, "#EXTM3U\r\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",URI=\"audio/index.m3u8?signed\"\r\n#EXT-X-STREAM-INF:BANDWIDTH=800000\r\n360p/index.m3u8?signed\n", string(master))
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Roverr/rtsp-stream/core/events"
+	"github.com/Roverr/rtsp-stream/core/limits"
+	"github.com/Roverr/rtsp-stream/core/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// subject returns the subject of the JWT token of the request to the endpoint, empty if authentication is disabled
+func (c *Controller) subject(r *http.Request, endpoint string) string {
+	claims := c.claims(r, endpoint)
+	if claims == nil {
+		return ""
+	}
+	return claims.Subject
+}
+
+// running returns the running streams as candidates of the limits
+func (c *Controller) running() []limits.Candidate {
+	candidates := []limits.Candidate{}
+	for id, stream := range c.registry.Streams() {
+		stream.Mux.Lock()
+		running := stream.Running
+		stream.Mux.Unlock()
+		if !running {
+			continue
+		}
+		candidates = append(candidates, limits.Candidate{
+			ID:       id,
+			LastSeen: c.viewers.LastSeen(id),
+			Pinned:   c.registry.Options(id).AlwaysOn,
+		})
+	}
+	return candidates
+}
+
+// admit checks the limits before the request starts the URI, stopping the stream evicted for it.
+// The returned function has to be called once the start is done.
+func (c *Controller) admit(r *http.Request, endpoint string, uri string) (func(), error) {
+	return c.admitSubject(c.subject(r, endpoint), uri)
+}
+
+// startAdmitted starts the URI within the limits for starts nobody requested, like restarts
+// of the supervisor and always on streams. The error of the limits is returned if they are reached.
+func (c *Controller) startAdmitted(uri string, start func()) error {
+	done, err := c.admitSubject("", uri)
+	if err != nil {
+		return err
+	}
+	defer done()
+	start()
+	return nil
+}
+
+// admitSubject checks the limits before the subject starts the URI, empty subject is only counted globally
+func (c *Controller) admitSubject(subject string, uri string) (func(), error) {
+	evict, err := c.limiter.Admit(uri, subject, c.running())
+	if err != nil {
+		logrus.Infof("%s is rejected: %s | Limits", uri, err)
+		c.metrics.Limited("rejected")
+		return nil, err
+	}
+	if evict != "" {
+		c.evict(evict)
+	}
+	return func() {
+		c.limiter.Done(uri)
+		if stream, ok := c.registry.GetByURI(uri); ok && stream.Running {
+			c.limiter.SetOwner(stream.ID, subject)
+		}
+	}, nil
+}
+
+// evict stops the stream to make room for a new one
+func (c *Controller) evict(id string) {
+	stream, ok := c.registry.Get(id)
+	if !ok {
+		return
+	}
+	logrus.Infof("%s is being evicted | Limits", id)
+	c.supervisor.Unwatch(id)
+	if err := stream.Stop(); err != nil {
+		logrus.Error(err)
+	}
+	c.metrics.Limited("evicted")
+	c.limiter.Remove(id)
+	c.snapshots.Remove(id)
+	c.metrics.StreamEvent(metrics.EventStop, id, c.registry.AliasOf(id))
+	c.events.Publish(events.Event{Type: events.Stopped, ID: id, Alias: c.registry.AliasOf(id), URI: stream.OriginalURI, Reason: "evicted"})
+	c.persist()
+}
+
+// limitState returns the limits and their usage seen by the caller of /list
+func (c *Controller) limitState(r *http.Request) LimitsDTO {
+	status := c.limiter.Status()
+	running := c.running()
+	state := LimitsDTO{Running: len(running), Max: status.Max, PerUser: status.PerUser, Policy: status.Policy}
+	subject := c.subject(r, "list")
+	if status.PerUser <= 0 || subject == "" {
+		return state
+	}
+	owned := 0
+	for _, candidate := range running {
+		if c.limiter.Owner(candidate.ID) == subject {
+			owned++
+		}
+	}
+	state.Owned = &owned
+	return state
+}
+
+// setLimitHeaders reports the limits and their usage in the headers of the response
+func (c *Controller) setLimitHeaders(w http.ResponseWriter, state LimitsDTO) {
+	w.Header().Set("X-Streams-Running", strconv.Itoa(state.Running))
+	if state.Max > 0 {
+		w.Header().Set("X-Streams-Limit", strconv.Itoa(state.Max))
+	}
+	if state.Owned == nil {
+		return
+	}
+	w.Header().Set("X-Streams-Quota", strconv.Itoa(state.PerUser))
+	w.Header().Set("X-Streams-Owned", strconv.Itoa(*state.Owned))
+}
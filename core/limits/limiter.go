@@ -0,0 +1,198 @@
+package limits
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Policies of starting streams over the limits
+const (
+	PolicyReject = "reject" // new streams are rejected
+	PolicyEvict  = "evict"  // the least recently watched stream is stopped for the new one
+)
+
+// ErrLimitReached describes an error when the maximum number of running streams is reached
+var ErrLimitReached = errors.New("Limit of running streams is reached")
+
+// ErrQuotaReached describes an error when the user runs the maximum number of streams allowed for them
+var ErrQuotaReached = errors.New("Quota of running streams is reached")
+
+// Candidate describes a running stream considered by the limits
+type Candidate struct {
+	ID       string
+	LastSeen time.Time // Last time the stream was watched
+	Pinned   bool      // Pinned streams are counted, but never evicted
+}
+
+// Status describes the configured limits
+type Status struct {
+	Max     int
+	PerUser int
+	Policy  string
+}
+
+// ILimiter describes the user panel of the limiter
+type ILimiter interface {
+	Admit(uri string, subject string, running []Candidate) (string, error)
+	Done(uri string)
+	SetOwner(id string, subject string)
+	Owner(id string) string
+	Remove(id string)
+	Status() Status
+}
+
+// Limiter decides if new streams can be started within the global limit and the quotas of users
+type Limiter struct {
+	mu      *sync.Mutex
+	max     int
+	perUser int
+	policy  string
+	owners  map[string]string // subject who started the stream, keyed by stream ID
+	pending map[string]*start // starts in flight, keyed by URI
+}
+
+// start describes a start in flight, holding a place until it is done
+type start struct {
+	subject string
+	count   int
+}
+
+// Type check
+var _ ILimiter = (*Limiter)(nil)
+
+// NewLimiter creates a new Limiter. 0 means unlimited for both max and perUser.
+func NewLimiter(max int, perUser int, policy string) *Limiter {
+	return &Limiter{
+		mu:      &sync.Mutex{},
+		max:     max,
+		perUser: perUser,
+		policy:  policy,
+		owners:  map[string]string{},
+		pending: map[string]*start{},
+	}
+}
+
+// Admit decides if the subject can start the URI next to the running streams.
+// Admitted starts hold a place until Done is called. With the evict policy it returns
+// the ID of the least recently watched stream that has to be stopped to make room.
+func (l *Limiter) Admit(uri string, subject string, running []Candidate) (string, error) {
+	if l == nil {
+		return "", nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.pending[uri]; ok {
+		s.count++
+		return "", nil
+	}
+
+	total := len(running) + len(l.pending)
+	evict := ""
+	if subject != "" && l.perUser > 0 {
+		owned := []Candidate{}
+		for _, c := range running {
+			if l.owners[c.ID] == subject {
+				owned = append(owned, c)
+			}
+		}
+		inFlight := 0
+		for _, s := range l.pending {
+			if s.subject == subject {
+				inFlight++
+			}
+		}
+		if len(owned)+inFlight >= l.perUser {
+			if evict = l.victim(owned); evict == "" {
+				return "", ErrQuotaReached
+			}
+			total--
+		}
+	}
+	if l.max > 0 && total >= l.max {
+		// a single start makes room for itself only
+		if evict != "" {
+			return "", ErrLimitReached
+		}
+		if evict = l.victim(running); evict == "" {
+			return "", ErrLimitReached
+		}
+	}
+	l.pending[uri] = &start{subject: subject, count: 1}
+	return evict, nil
+}
+
+// victim returns the least recently watched candidate that is not pinned,
+// or empty string if the policy does not allow evicting
+func (l *Limiter) victim(candidates []Candidate) string {
+	if l.policy != PolicyEvict {
+		return ""
+	}
+	var oldest *Candidate
+	for i, c := range candidates {
+		if c.Pinned {
+			continue
+		}
+		if oldest == nil || c.LastSeen.Before(oldest.LastSeen) {
+			oldest = &candidates[i]
+		}
+	}
+	if oldest == nil {
+		return ""
+	}
+	return oldest.ID
+}
+
+// Done releases the place held by an admitted start of the URI
+func (l *Limiter) Done(uri string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.pending[uri]
+	if !ok {
+		return
+	}
+	if s.count--; s.count <= 0 {
+		delete(l.pending, uri)
+	}
+}
+
+// SetOwner records the subject who started the stream, a restart hands it over to the new subject
+func (l *Limiter) SetOwner(id string, subject string) {
+	if l == nil || subject == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.owners[id] = subject
+}
+
+// Owner returns the subject who started the stream
+func (l *Limiter) Owner(id string) string {
+	if l == nil {
+		return ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.owners[id]
+}
+
+// Remove forgets the owner of the stream
+func (l *Limiter) Remove(id string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.owners, id)
+}
+
+// Status returns the configured limits
+func (l *Limiter) Status() Status {
+	if l == nil {
+		return Status{}
+	}
+	return Status{Max: l.max, PerUser: l.perUser, Policy: l.policy}
+}
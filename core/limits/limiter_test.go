@@ -0,0 +1,92 @@
+package limits
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmit(t *testing.T) {
+	now := time.Now()
+	running := []Candidate{
+		{ID: "a", LastSeen: now.Add(-time.Minute)},
+		{ID: "b", LastSeen: now.Add(-time.Hour), Pinned: true},
+		{ID: "c", LastSeen: now},
+	}
+
+	l := NewLimiter(3, 0, PolicyReject)
+	_, err := l.Admit("rtsp://host/1", "", running)
+	assert.Equal(t, ErrLimitReached, err)
+	evict, err := l.Admit("rtsp://host/1", "", running[:2])
+	assert.Nil(t, err)
+	assert.Empty(t, evict)
+	// the start in flight holds its place
+	_, err = l.Admit("rtsp://host/2", "", running[:2])
+	assert.Equal(t, ErrLimitReached, err)
+	_, err = l.Admit("rtsp://host/1", "", running[:2])
+	assert.Nil(t, err)
+	l.Done("rtsp://host/1")
+	l.Done("rtsp://host/1")
+	_, err = l.Admit("rtsp://host/2", "", running[:2])
+	assert.Nil(t, err)
+
+	l = NewLimiter(3, 0, PolicyEvict)
+	evict, err = l.Admit("rtsp://host/1", "", running)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", evict)
+	_, err = l.Admit("rtsp://host/2", "", running[1:2])
+	assert.Nil(t, err)
+	l.Done("rtsp://host/1")
+	l.Done("rtsp://host/2")
+	_, err = l.Admit("rtsp://host/1", "", running[1:2])
+	assert.Nil(t, err)
+	_, err = l.Admit("rtsp://host/2", "", []Candidate{{ID: "b", Pinned: true}, {ID: "d", Pinned: true}})
+	assert.Equal(t, ErrLimitReached, err)
+}
+
+func TestQuota(t *testing.T) {
+	now := time.Now()
+	running := []Candidate{
+		{ID: "a", LastSeen: now.Add(-time.Minute)},
+		{ID: "b", LastSeen: now.Add(-time.Hour)},
+		{ID: "c", LastSeen: now},
+	}
+
+	l := NewLimiter(0, 2, PolicyReject)
+	l.SetOwner("a", "bob")
+	l.SetOwner("a", "alice")
+	l.SetOwner("c", "alice")
+	l.SetOwner("b", "bob")
+	assert.Equal(t, "alice", l.Owner("a"))
+	_, err := l.Admit("rtsp://host/1", "alice", running)
+	assert.Equal(t, ErrQuotaReached, err)
+	_, err = l.Admit("rtsp://host/1", "bob", running)
+	assert.Nil(t, err)
+	_, err = l.Admit("rtsp://host/2", "", running)
+	assert.Nil(t, err)
+
+	l = NewLimiter(3, 2, PolicyEvict)
+	l.SetOwner("a", "alice")
+	l.SetOwner("c", "alice")
+	// bob's stream is watched the least, but alice makes room from her own streams
+	evict, err := l.Admit("rtsp://host/1", "alice", running)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", evict)
+
+	l.Remove("a")
+	assert.Empty(t, l.Owner("a"))
+	assert.Equal(t, Status{Max: 3, PerUser: 2, Policy: PolicyEvict}, l.Status())
+}
+
+func TestDisabledLimiter(t *testing.T) {
+	l := (*Limiter)(nil)
+	evict, err := l.Admit("rtsp://host/1", "alice", []Candidate{{ID: "a"}})
+	assert.Nil(t, err)
+	assert.Empty(t, evict)
+	l.Done("rtsp://host/1")
+	l.SetOwner("a", "alice")
+	assert.Empty(t, l.Owner("a"))
+	l.Remove("a")
+	assert.Equal(t, Status{}, l.Status())
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/limits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits(t *testing.T) {
+	c := newTestController()
+	c.limiter = limits.NewLimiter(2, 0, limits.PolicyReject)
+	old := newIdleStream("old", "rtsp://host/1")
+	recent := newIdleStream("recent", "rtsp://host/2")
+	c.registry.Add(old, "").Add(recent, "")
+	c.viewers.Hit("recent", "ip:1.2.3.4")
+
+	w := httptest.NewRecorder()
+	c.StartStreamHandler(w, httptest.NewRequest("POST", "/start", strings.NewReader(`{"uri":"rtsp://host/3"}`)), nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Len(t, c.registry.Streams(), 2)
+
+	// already running streams are not counted again
+	w = httptest.NewRecorder()
+	c.StartStreamHandler(w, httptest.NewRequest("POST", "/start", strings.NewReader(`{"uri":"rtsp://host/1"}`)), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	c.ListStreamHandler(w, httptest.NewRequest("GET", "/list", nil), nil)
+	assert.Equal(t, "2", w.Header().Get("X-Streams-Running"))
+	assert.Equal(t, "2", w.Header().Get("X-Streams-Limit"))
+	assert.Empty(t, w.Header().Get("X-Streams-Quota"))
+
+	w = httptest.NewRecorder()
+	c.ListStreamHandler(w, httptest.NewRequest("GET", "/list?limits=true", nil), nil)
+	list := ListDTO{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Streams, 2)
+	assert.Equal(t, LimitsDTO{Running: 2, Max: 2, Policy: limits.PolicyReject}, list.Limits)
+
+	c.limiter = limits.NewLimiter(2, 0, limits.PolicyEvict)
+	time.Sleep(time.Millisecond)
+	c.viewers.Hit("recent", "ip:1.2.3.4")
+	done, err := c.admit(httptest.NewRequest("POST", "/start", nil), "start", "rtsp://host/3")
+	assert.Nil(t, err)
+	done()
+	assert.False(t, old.Running)
+	assert.True(t, recent.Running)
+}
+
+func TestStartAdmitted(t *testing.T) {
+	c := newTestController()
+	c.limiter = limits.NewLimiter(1, 0, limits.PolicyReject)
+	c.registry.Add(newIdleStream("running", "rtsp://host/1"), "")
+	started := false
+	assert.Equal(t, limits.ErrLimitReached, c.startAdmitted("rtsp://host/2", func() { started = true }))
+	assert.False(t, started)
+
+	c.limiter = limits.NewLimiter(2, 0, limits.PolicyReject)
+	assert.Nil(t, c.startAdmitted("rtsp://host/2", func() { started = true }))
+	assert.True(t, started)
+}
+
+func TestRestartOwner(t *testing.T) {
+	c := newTestController()
+	c.limiter = limits.NewLimiter(0, 1, limits.PolicyReject)
+	stream := newIdleStream("cam", "rtsp://host/1")
+	c.registry.Add(stream, "")
+	c.limiter.SetOwner("cam", "alice")
+
+	w := httptest.NewRecorder()
+	c.StopStreamHandler(w, httptest.NewRequest("POST", "/stop", strings.NewReader(`{"id":"cam"}`)), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, c.limiter.Owner("cam"))
+
+	// bob restarts the stream alice started before, it counts against bob from now on
+	done, err := c.admitSubject("bob", "rtsp://host/1")
+	assert.Nil(t, err)
+	stream.Running = true
+	done()
+	assert.Equal(t, "bob", c.limiter.Owner("cam"))
+	_, err = c.admitSubject("bob", "rtsp://host/2")
+	assert.Equal(t, limits.ErrQuotaReached, err)
+	done, err = c.admitSubject("alice", "rtsp://host/2")
+	assert.Nil(t, err)
+	done()
+
+	// streams that died without being stopped are handed over as well
+	stream.Running = false
+	done, err = c.admitSubject("alice", "rtsp://host/1")
+	assert.Nil(t, err)
+	stream.Running = true
+	done()
+	assert.Equal(t, "alice", c.limiter.Owner("cam"))
+}
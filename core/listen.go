@@ -0,0 +1,163 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+)
+
+// syncPreloads registers the enabled listen entries of the yml file and the ones registered via the API,
+// the latter winning if both use the same alias. Always on entries are started. Callers have to hold listenMu.
+func (c *Controller) syncPreloads() {
+	wanted := map[string]config.ListenSetting{}
+	for _, item := range c.ymlListen {
+		if !item.Enabled {
+			continue
+		}
+		// profiles are read on startup, reloaded entries cannot refer to new ones
+		if _, err := c.spec.Profile(item.Profile); err != nil {
+			logrus.Errorf("Skipping preload of %s: %s | Preload", item.Uri, err)
+			continue
+		}
+		wanted[item.Alias] = item
+	}
+	for alias, item := range c.listen {
+		wanted[alias] = item
+	}
+	for alias := range c.registry.Preloads() {
+		if _, ok := wanted[alias]; !ok {
+			c.registry.RemovePreload(alias)
+			logrus.Infof("%s is not preloaded anymore | Preload", alias)
+		}
+	}
+	for alias, item := range wanted {
+		if current, ok := c.registry.Preload(alias); ok && current == item {
+			continue
+		}
+		c.registry.SetPreload(item)
+		logrus.Infof("%s is preloaded as %s | Preload", item.Uri, alias)
+	}
+	c.startAlwaysOn()
+}
+
+// restoreListen loads the listen entries registered via the API before the application stopped.
+// Callers have to hold listenMu.
+func (c *Controller) restoreListen() {
+	settings, err := c.store.LoadListen()
+	if err != nil {
+		logrus.Errorf("Could not load stored listen entries: %s | Store", err)
+		return
+	}
+	for _, setting := range settings {
+		c.listen[setting.Alias] = setting
+	}
+}
+
+// persistListen saves the listen entries registered via the API. Callers have to hold listenMu.
+func (c *Controller) persistListen() {
+	settings := make([]config.ListenSetting, 0, len(c.listen))
+	for _, setting := range c.listen {
+		settings = append(settings, setting)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Alias < settings[j].Alias })
+	if err := c.store.SaveListen(settings); err != nil {
+		logrus.Errorf("Could not save listen entries: %s | Store", err)
+	}
+}
+
+// GetListenHandler is the HTTP handler of the GET /listen/{alias} call.
+// Returns the definition of the lazily started stream registered under the alias.
+func (c *Controller) GetListenHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !c.isAuthenticated(r, "listen") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	setting, ok := c.registry.Preload(ps.ByName("alias"))
+	if !ok {
+		c.sendError(w, errors.New("Unknown alias"), http.StatusNotFound)
+		return
+	}
+	c.sendListen(w, http.StatusOK, setting)
+}
+
+// PutListenHandler is the HTTP handler of the PUT /listen/{alias} call.
+// Registers or updates a stream that is started on its first request under the alias.
+func (c *Controller) PutListenHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !c.isAuthenticated(r, "listen") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var dto StreamDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		c.sendError(w, err, http.StatusBadRequest)
+		return
+	}
+	setting := config.ListenSetting{Enabled: true, Uri: dto.URI, Alias: ps.ByName("alias"), StreamOptions: dto.StreamOptions}
+	yml := config.EndpointYML{Listen: []config.ListenSetting{setting}, Profiles: c.spec.Profiles}
+	if err := yml.Validate(); err != nil {
+		c.sendError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// the alias is checked and taken under the same lock, so concurrent requests cannot both take it
+	c.listenMu.Lock()
+	defer c.listenMu.Unlock()
+	if id, ok := c.registry.ResolveAlias(setting.Alias); ok {
+		if stream, ok := c.registry.Get(id); ok && stream.OriginalURI != setting.Uri {
+			c.sendError(w, fmt.Errorf("%s is used by another stream", setting.Alias), http.StatusConflict)
+			return
+		}
+	}
+	_, existed := c.registry.Preload(setting.Alias)
+	c.listen[setting.Alias] = setting
+	c.syncPreloads()
+	c.persistListen()
+
+	status := http.StatusCreated
+	if existed {
+		status = http.StatusOK
+	}
+	c.sendListen(w, status, setting)
+}
+
+// DeleteListenHandler is the HTTP handler of the DELETE /listen/{alias} call.
+// Removes the definition registered via the API, a stream already started under the alias is left untouched.
+func (c *Controller) DeleteListenHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !c.isAuthenticated(r, "listen") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	alias := ps.ByName("alias")
+	c.listenMu.Lock()
+	defer c.listenMu.Unlock()
+	if _, ok := c.listen[alias]; !ok {
+		if _, ok := c.registry.Preload(alias); ok {
+			c.sendError(w, fmt.Errorf("%s is defined in the configuration file", alias), http.StatusConflict)
+			return
+		}
+		c.sendError(w, errors.New("Unknown alias"), http.StatusNotFound)
+		return
+	}
+	delete(c.listen, alias)
+	c.syncPreloads()
+	c.persistListen()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendListen sends the listen entry in the format it is registered with
+func (c *Controller) sendListen(w http.ResponseWriter, status int, setting config.ListenSetting) {
+	b, err := json.Marshal(StreamDTO{URI: setting.Uri, Alias: setting.Alias, StreamOptions: setting.StreamOptions})
+	if err != nil {
+		c.sendError(w, ErrUnexpected, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/store"
+	"github.com/julienschmidt/httprouter"
+	"github.com/riltech/streamer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenHandlers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listen")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileStore, err := store.NewFileStore(dir)
+	assert.Nil(t, err)
+	c := newTestController()
+	c.store = fileStore
+	c.ymlListen = []config.ListenSetting{{Enabled: true, Uri: "rtsp://host/yml", Alias: "yml"}}
+	c.syncPreloads()
+	params := func(alias string) httprouter.Params {
+		return httprouter.Params{{Key: "alias", Value: alias}}
+	}
+
+	w := httptest.NewRecorder()
+	c.PutListenHandler(w, httptest.NewRequest(http.MethodPut, "/listen/camera", strings.NewReader(`{"uri":"rtsp://host/1"}`)), params("camera"))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = httptest.NewRecorder()
+	c.PutListenHandler(w, httptest.NewRequest(http.MethodPut, "/listen/camera", strings.NewReader(`{"uri":"rtsp://host/2","record":true}`)), params("camera"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	c.PutListenHandler(w, httptest.NewRequest(http.MethodPut, "/listen/camera", strings.NewReader(`{"uri":"host"}`)), params("camera"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = httptest.NewRecorder()
+	c.PutListenHandler(w, httptest.NewRequest(http.MethodPut, "/listen/camera", strings.NewReader(`{"uri":"rtsp://host/2","profile":"unknown"}`)), params("camera"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	c.GetListenHandler(w, httptest.NewRequest(http.MethodGet, "/listen/camera", nil), params("camera"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"uri":"rtsp://host/2","alias":"camera","record":true}`, w.Body.String())
+
+	settings, err := fileStore.LoadListen()
+	assert.Nil(t, err)
+	assert.Equal(t, []config.ListenSetting{{Enabled: true, Uri: "rtsp://host/2", Alias: "camera", StreamOptions: config.StreamOptions{Record: true}}}, settings)
+
+	// aliases of running streams cannot point to another camera
+	c.registry.Add(&streamer.Stream{ID: "id", OriginalURI: "rtsp://host/3"}, "taken")
+	w = httptest.NewRecorder()
+	c.PutListenHandler(w, httptest.NewRequest(http.MethodPut, "/listen/taken", strings.NewReader(`{"uri":"rtsp://host/4"}`)), params("taken"))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	c.DeleteListenHandler(w, httptest.NewRequest(http.MethodDelete, "/listen/yml", nil), params("yml"))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = httptest.NewRecorder()
+	c.DeleteListenHandler(w, httptest.NewRequest(http.MethodDelete, "/listen/camera", nil), params("camera"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = httptest.NewRecorder()
+	c.DeleteListenHandler(w, httptest.NewRequest(http.MethodDelete, "/listen/camera", nil), params("camera"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	c.GetListenHandler(w, httptest.NewRequest(http.MethodGet, "/listen/camera", nil), params("camera"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	settings, err = fileStore.LoadListen()
+	assert.Nil(t, err)
+	assert.Empty(t, settings)
+	assert.Len(t, c.registry.Preloads(), 1)
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/julienschmidt/httprouter"
+)
+
+// TokenCookie is the name of the cookie holding the token set by the login endpoint
+const TokenCookie = "rtsp_stream_token"
+
+// TokenParam is the query parameter holding the token
+const TokenParam = "access_token"
+
+// LoginDTO describes the body of the /login call
+type LoginDTO struct {
+	Token string `json:"token"`
+}
+
+// token returns the token of the request to the endpoint
+func (c *Controller) token(r *http.Request, endpoint string) string {
+	token, _ := c.tokenCarrier(r, endpoint)
+	return token
+}
+
+// tokenCarrier returns the token of the request from the first carrier of the endpoint holding one,
+// together with the name of the carrier
+func (c *Controller) tokenCarrier(r *http.Request, endpoint string) (string, string) {
+	for _, carrier := range c.endpoint(endpoint).TokenCarriers() {
+		switch carrier {
+		case config.CarrierHeader:
+			if value := r.Header.Get("Authorization"); value != "" {
+				return value, carrier
+			}
+		case config.CarrierCookie:
+			if cookie, err := r.Cookie(TokenCookie); err == nil && cookie.Value != "" {
+				return cookie.Value, carrier
+			}
+		case config.CarrierQuery:
+			if value := r.URL.Query().Get(TokenParam); value != "" {
+				return value, carrier
+			}
+		}
+	}
+	return "", ""
+}
+
+// queryToken returns the token of the request as query parameter if it was sent in the query,
+// so redirects and the files listed in playlists are authenticated the same way
+func (c *Controller) queryToken(r *http.Request, endpoint string) url.Values {
+	if token, carrier := c.tokenCarrier(r, endpoint); c.spec.JWTEnabled && carrier == config.CarrierQuery {
+		return url.Values{TokenParam: {token}}
+	}
+	return url.Values{}
+}
+
+// secure shows if the request reached the application or its proxy via HTTPS
+func secure(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// LoginHandler is the HTTP handler of the POST /login call.
+// Exchanges the token in the Authorization header or in the body for a cookie,
+// so browser players can authenticate on the endpoints accepting the cookie carrier.
+func (c *Controller) LoginHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !c.spec.JWTEnabled {
+		c.sendError(w, errors.New("Authentication is disabled"), http.StatusNotFound)
+		return
+	}
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		var dto LoginDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			c.sendError(w, err, http.StatusBadRequest)
+			return
+		}
+		token = dto.Token
+	}
+	token = strings.TrimPrefix(token, "Bearer ")
+	// scopes and secrets are checked by the endpoints the cookie is used at
+	valid, claims := c.jwt.Validate(token)
+	if valid == nil || !valid.Valid {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	cookie := &http.Cookie{
+		Name:     TokenCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secure(r),
+		SameSite: http.SameSiteLaxMode,
+	}
+	if claims.ExpiresAt != 0 {
+		cookie.Expires = time.Unix(claims.ExpiresAt, 0)
+	}
+	http.SetCookie(w, cookie)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutHandler is the HTTP handler of the DELETE /login call, removes the cookie set by the login
+func (c *Controller) LogoutHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     TokenCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenCarriers(t *testing.T) {
+	c := newTestController()
+	c.endpoints.Store(config.Endpoints{
+		Static:   config.EndpointSetting{Carriers: []string{config.CarrierQuery, config.CarrierCookie}},
+		Snapshot: config.EndpointSetting{Carriers: []string{config.CarrierCookie, config.CarrierHeader}},
+	})
+	r := httptest.NewRequest(http.MethodGet, "/stream/id/index.m3u8?access_token=query", nil)
+	r.Header.Set("Authorization", "Bearer header")
+	r.AddCookie(&http.Cookie{Name: TokenCookie, Value: "cookie"})
+
+	token, carrier := c.tokenCarrier(r, "static")
+	assert.Equal(t, "query", token)
+	assert.Equal(t, config.CarrierQuery, carrier)
+	assert.Equal(t, "cookie", c.token(r, "snapshot"))
+	assert.Equal(t, "Bearer header", c.token(r, "list"))
+	assert.Empty(t, c.token(httptest.NewRequest(http.MethodGet, "/stream/id/index.m3u8", nil), "static"))
+}
+
+func TestLogin(t *testing.T) {
+	storeDir, err := ioutil.TempDir("", "login")
+	assert.Nil(t, err)
+	defer os.RemoveAll(storeDir)
+	assert.Nil(t, os.MkdirAll(filepath.Join(storeDir, "id"), os.ModePerm))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(storeDir, "id", "index.m3u8"), []byte("#EXTM3U\n#EXTINF:2.000000,\n0.ts\n"), 0644))
+
+	c := newAuthController(t)
+	c.fileServer = http.FileServer(http.Dir(storeDir))
+	c.registry.Add(newIdleStream("id", "rtsp://host/1"), "camera")
+	c.endpoints.Store(config.Endpoints{Static: config.EndpointSetting{Enabled: true, Carriers: []string{config.CarrierCookie, config.CarrierQuery}}})
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		file := strings.TrimPrefix(r.URL.Path, "/stream")
+		c.StaticFileHandler(w, r, httprouter.Params{{Key: "filepath", Value: file}})
+		return w
+	}
+
+	expires := time.Now().Add(time.Hour).Unix()
+	w := httptest.NewRecorder()
+	c.LoginHandler(w, newTokenRequest(t, http.MethodPost, "/login", "", jwt.MapClaims{"exp": expires}), nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, TokenCookie, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, expires, cookies[0].Expires.Unix())
+
+	w = httptest.NewRecorder()
+	c.LoginHandler(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"token":"invalid"}`)), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// the header is not accepted by the endpoint anymore
+	assert.Equal(t, http.StatusForbidden, serve(newTokenRequest(t, http.MethodGet, "/stream/id/index.m3u8", "", jwt.MapClaims{})).Code)
+	r := httptest.NewRequest(http.MethodGet, "/stream/id/index.m3u8", nil)
+	r.AddCookie(cookies[0])
+	assert.Equal(t, http.StatusOK, serve(r).Code)
+
+	// tokens in the query are passed on to the files of the playlist
+	token := strings.TrimPrefix(newTokenRequest(t, http.MethodGet, "/", "", jwt.MapClaims{}).Header.Get("Authorization"), "Bearer ")
+	w = serve(httptest.NewRequest(http.MethodGet, "/stream/camera/index.m3u8?access_token="+token, nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/stream/id/index.m3u8?access_token="+token, w.Header().Get("Location"))
+	w = serve(httptest.NewRequest(http.MethodGet, w.Header().Get("Location"), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "\n0.ts?access_token="+token+"\n")
+
+	w = httptest.NewRecorder()
+	c.LogoutHandler(w, httptest.NewRequest(http.MethodDelete, "/login", nil), nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+}
+
+func TestSnapshotQueryToken(t *testing.T) {
+	c := newAuthController(t)
+	c.registry.Add(newIdleStream("id", "rtsp://host/1"), "camera")
+	c.endpoints.Store(config.Endpoints{Snapshot: config.EndpointSetting{Enabled: true, Carriers: []string{config.CarrierQuery}}})
+	token := strings.TrimPrefix(newTokenRequest(t, http.MethodGet, "/", "", jwt.MapClaims{}).Header.Get("Authorization"), "Bearer ")
+
+	// the redirect of the alias keeps the token, so images work without headers
+	w := httptest.NewRecorder()
+	c.SnapshotHandler(w, httptest.NewRequest(http.MethodGet, "/snapshot/camera.jpg?access_token="+token, nil), httprouter.Params{{Key: "file", Value: "camera.jpg"}})
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/snapshot/id.jpg?access_token="+token, w.Header().Get("Location"))
+	assert.True(t, c.isAuthenticated(httptest.NewRequest(http.MethodGet, w.Header().Get("Location"), nil), "snapshot"))
+}
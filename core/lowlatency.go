@@ -0,0 +1,160 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/hls"
+	"github.com/Roverr/rtsp-stream/core/transcoder"
+)
+
+// pollInterval is the time between checks while a request waits for new media
+const pollInterval = 50 * time.Millisecond
+
+// serveLowLatency serves files of streams in low latency mode.
+// Playlists are rendered as LL-HLS and held back until the requested part exists,
+// full segments are assembled from their partial segments.
+func (c *Controller) serveLowLatency(w http.ResponseWriter, req *http.Request, profile config.Profile) {
+	ll := hls.LowLatency{
+		PartTarget:      profile.PartLength,
+		PartsPerSegment: profile.PartsPerSegment(),
+		Independent:     transcoder.ForcesKeyframes(profile),
+	}
+	name := path.Clean("/" + req.URL.Path)
+	file := filepath.Join(c.spec.StoreDir, filepath.FromSlash(name))
+	base := path.Base(name)
+	timeout := 3 * ll.TargetDuration()
+
+	if base == "index.m3u8" {
+		c.servePlaylist(w, req, ll, file, timeout)
+		return
+	}
+	var msn int
+	if _, err := fmt.Sscanf(base, hls.SegmentFormat, &msn); err == nil && fmt.Sprintf(hls.SegmentFormat, msn) == base {
+		c.serveSegment(w, req, ll, filepath.Join(filepath.Dir(file), "index.m3u8"), msn)
+		return
+	}
+	var part int
+	if _, err := fmt.Sscanf(base, hls.PartFormat, &part); err == nil && fmt.Sprintf(hls.PartFormat, part) == base {
+		// parts announced by preload hints are requested before ffmpeg finishes them
+		waitFor(req, timeout, func() bool {
+			_, err := os.Stat(file)
+			return err == nil
+		})
+	}
+	c.fileServer.ServeHTTP(w, req)
+}
+
+// servePlaylist serves the LL-HLS playlist once it contains the part requested by
+// the _HLS_msn and _HLS_part query parameters of a blocking playlist reload
+func (c *Controller) servePlaylist(w http.ResponseWriter, req *http.Request, ll hls.LowLatency, file string, timeout time.Duration) {
+	msn, part := -1, -1
+	query := req.URL.Query()
+	if value := query.Get("_HLS_msn"); value != "" {
+		var err error
+		if msn, err = strconv.Atoi(value); err != nil || msn < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("_HLS_part"); value != "" {
+		var err error
+		if part, err = strconv.Atoi(value); err != nil || part < 0 || msn < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if msn < 0 {
+		timeout = 0
+	}
+
+	var playlist *hls.Playlist
+	ready := waitFor(req, timeout, func() bool {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return false
+		}
+		current, err := hls.Parse(data)
+		if err != nil {
+			return false
+		}
+		playlist = current
+		// requests too far in the future are never going to be satisfied in time
+		if msn > ll.Current(playlist)+2 {
+			return true
+		}
+		return msn < 0 || ll.Ready(playlist, msn, part)
+	})
+	if playlist == nil {
+		http.NotFound(w, req)
+		return
+	}
+	if msn > ll.Current(playlist)+2 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(ll.Render(playlist))
+}
+
+// serveSegment serves a full segment by concatenating its partial segments
+func (c *Controller) serveSegment(w http.ResponseWriter, req *http.Request, ll hls.LowLatency, file string, msn int) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	playlist, err := hls.Parse(data)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	parts, ok := ll.Parts(playlist, msn)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	var segment bytes.Buffer
+	for _, part := range parts {
+		b, err := ioutil.ReadFile(filepath.Join(filepath.Dir(file), path.Base(part)))
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		segment.Write(b)
+	}
+	w.Header().Set("Content-Type", "video/iso.segment")
+	http.ServeContent(w, req, path.Base(req.URL.Path), time.Time{}, bytes.NewReader(segment.Bytes()))
+}
+
+// waitFor checks the condition until it is met, the timeout passes or the client goes away.
+// Returns if the condition was met.
+func waitFor(req *http.Request, timeout time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if condition() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		select {
+		case <-req.Context().Done():
+			return false
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeParts writes a playlist listing the given number of partial segments into dir
+func writeParts(t *testing.T, dir string, count int) {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-TARGETDURATION:1\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-MAP:URI=\"init.mp4\"\n")
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&b, "#EXTINF:0.500000,\npart%d.m4s\n", i)
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("part%d.m4s", i)), []byte{byte(i)}, 0644))
+	}
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "index.m3u8"), b.Bytes(), 0644))
+}
+
+func TestServeLowLatency(t *testing.T) {
+	storeDir, err := ioutil.TempDir("", "lowlatency")
+	assert.Nil(t, err)
+	defer os.RemoveAll(storeDir)
+	dir := filepath.Join(storeDir, "id")
+	assert.Nil(t, os.MkdirAll(dir, os.ModePerm))
+	writeParts(t, dir, 5)
+
+	c := newTestController()
+	c.spec.StoreDir = storeDir
+	profile := config.Profile{LowLatency: true, SegmentLength: time.Second, PartLength: 500 * time.Millisecond}
+	serve := func(uri string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c.serveLowLatency(w, httptest.NewRequest(http.MethodGet, uri, nil), profile)
+		return w
+	}
+
+	w := serve("/id/index.m3u8")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"part5.m4s\"")
+
+	w = serve("/id/segment1.m4s")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []byte{2, 3}, w.Body.Bytes())
+	assert.Equal(t, http.StatusNotFound, serve("/id/segment2.m4s").Code)
+
+	assert.Equal(t, http.StatusBadRequest, serve("/id/index.m3u8?_HLS_part=1").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("/id/index.m3u8?_HLS_msn=abc").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("/id/index.m3u8?_HLS_msn=10").Code)
+
+	// blocks until the requested part is written
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		writeParts(t, dir, 7)
+	}()
+	w = serve("/id/index.m3u8?_HLS_msn=3&_HLS_part=0")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "part6.m4s")
+
+	start := time.Now()
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/id/index.m3u8?_HLS_msn=4").Code)
+	assert.True(t, time.Since(start) >= 3*time.Second)
+}
+
+func TestServeLowLatencyAlias(t *testing.T) {
+	storeDir, err := ioutil.TempDir("", "lowlatency")
+	assert.Nil(t, err)
+	defer os.RemoveAll(storeDir)
+	dir := filepath.Join(storeDir, "id")
+	assert.Nil(t, os.MkdirAll(dir, os.ModePerm))
+	writeParts(t, dir, 5)
+
+	c := newTestController()
+	c.spec.StoreDir = storeDir
+	c.spec.Profiles = map[string]config.Profile{"live": {LowLatency: true, SegmentLength: time.Second, PartLength: 500 * time.Millisecond}}
+	c.registry.Add(newRunningStream("id", "rtsp://host/1"), "camera")
+	c.registry.SetOptions("id", config.StreamOptions{Profile: "live"})
+	serve := func(uri string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		file := strings.TrimPrefix(strings.SplitN(uri, "?", 2)[0], "/stream")
+		c.StaticFileHandler(w, httptest.NewRequest(http.MethodGet, uri, nil), httprouter.Params{{Key: "filepath", Value: file}})
+		return w
+	}
+
+	// blocking reloads keep their parameters through the redirect of the alias
+	w := serve("/stream/camera/index.m3u8?_HLS_msn=3&_HLS_part=0")
+	assert.Equal(t, http.StatusFound, w.Code)
+	location := w.Header().Get("Location")
+	assert.Equal(t, "/stream/id/index.m3u8?_HLS_msn=3&_HLS_part=0", location)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		writeParts(t, dir, 7)
+	}()
+	w = serve(location)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "part6.m4s")
+}
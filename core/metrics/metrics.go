@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/blacklist"
+	"github.com/Roverr/rtsp-stream/core/limits"
+	"github.com/Roverr/rtsp-stream/core/registry"
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "rtsp_stream"
+
+// Event describes a lifecycle event of a stream that is counted
+type Event string
+
+const (
+	// EventStart is counted when a new transcoding process starts
+	EventStart Event = "start"
+	// EventStop is counted when a transcoding process is stopped
+	EventStop Event = "stop"
+	// EventRestart is counted when a known stream is started again
+	EventRestart Event = "restart"
+	// EventTimeout is counted when a transcoding process could not start in time
+	EventTimeout Event = "timeout"
+)
+
+// IMetrics describes the user panel of Metrics
+type IMetrics interface {
+	StreamEvent(event Event, id string, alias string)
+	SegmentRequest(id string)
+	Limited(action string)
+	Instrument(route string, handle httprouter.Handle) httprouter.Handle
+	Handler() http.Handler
+}
+
+// Metrics implements IMetrics and holds every collector of the application
+type Metrics struct {
+	registry        *prometheus.Registry
+	streamEvents    *prometheus.CounterVec
+	segmentRequests *prometheus.CounterVec
+	limitEvents     *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+}
+
+// Type check
+var _ IMetrics = (*Metrics)(nil)
+
+// NewMetrics creates a new Metrics instance reading stream, blacklist and limit state on every scrape
+func NewMetrics(streams registry.IRegistry, list blacklist.IList, limiter limits.ILimiter) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		streamEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "stream_events_total",
+			Help:      "Number of start, stop, restart and timeout events per stream.",
+		}, []string{"event", "id", "alias"}),
+		segmentRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "segment_requests_total",
+			Help:      "Number of video segment requests served per stream.",
+		}, []string{"id"}),
+		limitEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "limit_events_total",
+			Help:      "Number of starts rejected and streams evicted because of the limits.",
+		}, []string{"action"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests per route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+	}
+	m.registry.MustRegister(
+		m.streamEvents,
+		m.segmentRequests,
+		m.limitEvents,
+		m.httpDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "streams_running",
+			Help:      "Number of streams with a running transcoding process.",
+		}, func() float64 {
+			running := 0
+			for _, stream := range streams.Streams() {
+				stream.Mux.Lock()
+				if stream.Running {
+					running++
+				}
+				stream.Mux.Unlock()
+			}
+			return float64(running)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "streams_known",
+			Help:      "Number of streams known by the application.",
+		}, func() float64 {
+			return float64(len(streams.Streams()))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "streams_preloaded",
+			Help:      "Number of registered preloads, including the started ones.",
+		}, func() float64 {
+			return float64(len(streams.Preloads()))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "streams_limit",
+			Help:      "Maximum number of running streams, 0 means unlimited.",
+		}, func() float64 {
+			return float64(limiter.Status().Max)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "blacklist_bans_total",
+			Help:      "Number of times an URI got banned by the blacklist.",
+		}, func() float64 {
+			return float64(list.BanCount())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "blacklist_banned",
+			Help:      "Number of URIs currently banned by the blacklist.",
+		}, func() float64 {
+			return float64(list.BannedCount())
+		}),
+	)
+	return m
+}
+
+// StreamEvent counts a lifecycle event for the given stream
+func (m *Metrics) StreamEvent(event Event, id string, alias string) {
+	if m == nil {
+		return
+	}
+	m.streamEvents.WithLabelValues(string(event), id, alias).Inc()
+}
+
+// SegmentRequest counts a video segment request for the given stream
+func (m *Metrics) SegmentRequest(id string) {
+	if m == nil {
+		return
+	}
+	m.segmentRequests.WithLabelValues(id).Inc()
+}
+
+// Limited counts a start rejected or a stream evicted because of the limits
+func (m *Metrics) Limited(action string) {
+	if m == nil {
+		return
+	}
+	m.limitEvents.WithLabelValues(action).Inc()
+}
+
+// Instrument wraps the handle to observe the latency of its requests under the given route
+func (m *Metrics) Instrument(route string, handle httprouter.Handle) httprouter.Handle {
+	if m == nil {
+		return handle
+	}
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handle(rec, r, ps)
+		m.httpDuration.
+			WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler returns the HTTP handler exposing the collected metrics
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader stores the status code before writing it
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
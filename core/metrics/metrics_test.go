@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/blacklist"
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/limits"
+	"github.com/Roverr/rtsp-stream/core/registry"
+	"github.com/julienschmidt/httprouter"
+	"github.com/riltech/streamer"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	b, err := ioutil.ReadAll(w.Body)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func TestMetrics(t *testing.T) {
+	reg := registry.NewRegistry()
+	reg.Add(&streamer.Stream{ID: "id", OriginalURI: "rtsp://host/1", Running: true, Mux: &sync.Mutex{}}, "camera")
+	reg.Add(&streamer.Stream{ID: "id2", OriginalURI: "rtsp://host/2", Mux: &sync.Mutex{}}, "")
+	reg.SetPreload(config.ListenSetting{Enabled: true, Uri: "rtsp://host/3", Alias: "preload"})
+	list := blacklist.NewList(time.Hour, 0)
+	list.AddOrIncrease("rtsp://host/4").AddOrIncrease("rtsp://host/4")
+
+	m := NewMetrics(reg, list, limits.NewLimiter(10, 0, limits.PolicyReject))
+	m.StreamEvent(EventStart, "id", "camera")
+	m.Limited("rejected")
+	m.StreamEvent(EventTimeout, "id2", "")
+	m.SegmentRequest("id")
+	handle := m.Instrument("/list", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	handle(httptest.NewRecorder(), httptest.NewRequest("GET", "/list", nil), nil)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, "rtsp_stream_streams_running 1")
+	assert.Contains(t, body, "rtsp_stream_streams_known 2")
+	assert.Contains(t, body, "rtsp_stream_streams_preloaded 1")
+	assert.Contains(t, body, "rtsp_stream_blacklist_bans_total 1")
+	assert.Contains(t, body, "rtsp_stream_blacklist_banned 1")
+	assert.Contains(t, body, "rtsp_stream_streams_limit 10")
+	assert.Contains(t, body, `rtsp_stream_limit_events_total{action="rejected"} 1`)
+	assert.Contains(t, body, `rtsp_stream_stream_events_total{alias="camera",event="start",id="id"} 1`)
+	assert.Contains(t, body, `rtsp_stream_stream_events_total{alias="",event="timeout",id="id2"} 1`)
+	assert.Contains(t, body, `rtsp_stream_segment_requests_total{id="id"} 1`)
+	assert.Contains(t, body, `rtsp_stream_http_request_duration_seconds_count{code="403",method="GET",route="/list"} 1`)
+}
+
+func TestDisabledMetrics(t *testing.T) {
+	m := (*Metrics)(nil)
+	m.StreamEvent(EventStart, "id", "")
+	m.SegmentRequest("id")
+	m.Limited("evicted")
+	called := false
+	handle := m.Instrument("/list", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		called = true
+	})
+	handle(httptest.NewRecorder(), httptest.NewRequest("GET", "/list", nil), nil)
+	assert.True(t, called)
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/recording"
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+)
+
+// RecordingDTO describes a recorded file of a stream
+type RecordingDTO struct {
+	ID    string    `json:"id"`
+	Alias string    `json:"alias"`
+	URI   string    `json:"uri"`
+	Start time.Time `json:"start"`
+	Size  int64     `json:"size"`
+}
+
+// cleanRecordings enforces the retention of recordings and prepares
+// the directories of the upcoming day for the streams being recorded
+func (c *Controller) cleanRecordings() {
+	removed, err := c.janitor.Clean(time.Now())
+	if err != nil {
+		logrus.Errorf("Could not clean recordings: %s | Recording", err)
+	}
+	for _, rec := range removed {
+		logrus.Infof("%s is removed | Recording", rec.Path)
+	}
+}
+
+// prepareRecording creates the dated directories of the stream, so ffmpeg can write into them
+func (c *Controller) prepareRecording(id string) {
+	if err := recording.Prepare(filepath.Join(c.spec.RecordDir, id), time.Now()); err != nil {
+		logrus.Errorf("Could not create recording directory of %s: %s | Recording", id, err)
+	}
+}
+
+// prepareRecordings creates the dated directories of the running recorded streams for the next day
+func (c *Controller) prepareRecordings() {
+	for id, stream := range c.registry.Streams() {
+		stream.Mux.Lock()
+		running := stream.Running
+		stream.Mux.Unlock()
+		if running && c.registry.Options(id).Record {
+			c.prepareRecording(id)
+		}
+	}
+}
+
+// ListRecordingsHandler is the HTTP handler of the GET /recordings call.
+// The id query parameter filters for the recordings of a single stream by its ID or alias.
+func (c *Controller) ListRecordingsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !c.isAuthenticated(r, "recordings") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id != "" && !c.canAccessRef(r, "recordings", id) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if resolved, ok := c.registry.ResolveAlias(id); ok {
+		id = resolved
+	}
+	recordings, err := recording.List(c.spec.RecordDir)
+	if err != nil {
+		logrus.Error(err)
+		c.sendError(w, ErrUnexpected, http.StatusInternalServerError)
+		return
+	}
+	dto := []*RecordingDTO{}
+	claims := c.claims(r, "recordings")
+	for _, rec := range recordings {
+		if id != "" && rec.ID != id {
+			continue
+		}
+		// recordings are listed only for the streams the caller may access
+		uri := ""
+		if stream, ok := c.registry.Get(rec.ID); ok {
+			uri = stream.OriginalURI
+		}
+		alias := c.registry.AliasOf(rec.ID)
+		if !c.canAccess(claims, rec.ID, alias, uri) {
+			continue
+		}
+		dto = append(dto, &RecordingDTO{
+			ID:    rec.ID,
+			Alias: alias,
+			URI:   fmt.Sprintf("/recordings/%s", rec.Path),
+			Start: rec.Start,
+			Size:  rec.Size,
+		})
+	}
+	b, err := json.Marshal(dto)
+	if err != nil {
+		c.sendError(w, ErrUnexpected, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// RecordingFileHandler is the HTTP handler of the GET /recordings/{id}/{file} call
+func (c *Controller) RecordingFileHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !c.isAuthenticated(r, "recordings") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	r.URL.Path = ps.ByName("filepath")
+	if !c.canAccessRef(r, "recordings", c.getIDByPath(r.URL.Path)) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	c.recordingServer.ServeHTTP(w, r)
+}
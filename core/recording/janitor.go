@@ -0,0 +1,85 @@
+package recording
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// IJanitor describes the user panel of the recording retention
+type IJanitor interface {
+	Clean(now time.Time) ([]Recording, error)
+}
+
+// Janitor enforces the retention policies of the recordings
+type Janitor struct {
+	dir           string
+	retention     time.Duration
+	quota         int64
+	segmentLength time.Duration
+}
+
+// Type check
+var _ IJanitor = (*Janitor)(nil)
+
+// NewJanitor creates a new Janitor for the given recording directory.
+// Recordings older than retention are removed, then the oldest ones until
+// the total size fits into quota bytes. Zero values turn off the given policy.
+// Files written within segmentLength may still be recorded into, they are never removed.
+func NewJanitor(dir string, retention time.Duration, quota int64, segmentLength time.Duration) *Janitor {
+	return &Janitor{dir, retention, quota, segmentLength}
+}
+
+// Clean removes the recordings violating the retention policies and returns them
+func (j *Janitor) Clean(now time.Time) ([]Recording, error) {
+	if j == nil {
+		return nil, nil
+	}
+	recordings, err := List(j.dir)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, recording := range recordings {
+		total += recording.Size
+	}
+	removed := []Recording{}
+	for _, recording := range recordings {
+		expired := j.retention > 0 && now.Sub(recording.Start) > j.retention
+		overQuota := j.quota > 0 && total > j.quota
+		if !expired && !overQuota {
+			// recordings are ordered by their start, the rest is newer
+			break
+		}
+		if now.Sub(recording.Modified) < j.segmentLength {
+			// ffmpeg is still writing the file, later ones of other streams can be finished already
+			continue
+		}
+		if err := j.remove(recording, now); err != nil {
+			return removed, err
+		}
+		total -= recording.Size
+		removed = append(removed, recording)
+	}
+	return removed, nil
+}
+
+// remove deletes the file of the recording and the dated directories
+// left empty behind it, except the ones prepared for today and later
+func (j *Janitor) remove(recording Recording, now time.Time) error {
+	path := filepath.Join(j.dir, filepath.FromSlash(recording.Path))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if recording.Start.Format(dayLayout) >= now.Format(dayLayout) {
+		return nil
+	}
+	// day, month and year directories, removal fails on the ones still holding recordings
+	for i := 0; i < 3; i++ {
+		path = filepath.Dir(path)
+		if os.Remove(path) != nil {
+			break
+		}
+	}
+	return nil
+}
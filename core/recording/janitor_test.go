@@ -0,0 +1,84 @@
+package recording
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJanitorRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-janitor")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 1, 10, 12, 0, 0, 0, time.Local)
+	write(t, dir, "id", now.AddDate(0, 0, -3), 1)
+	write(t, dir, "id", now.AddDate(0, 0, -1), 1)
+	assert.Nil(t, Prepare(filepath.Join(dir, "id"), now))
+
+	removed, err := NewJanitor(dir, 48*time.Hour, 0, 0).Clean(now)
+	assert.Nil(t, err)
+	assert.Len(t, removed, 1)
+	assert.Equal(t, "id/2020/01/07/12-00-00.mp4", removed[0].Path)
+
+	// empty directories of past days are removed, prepared ones are kept
+	_, err = os.Stat(filepath.Join(dir, "id", "2020", "01", "07"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "id", "2020", "01", "11"))
+	assert.Nil(t, err)
+
+	recordings, err := List(dir)
+	assert.Nil(t, err)
+	assert.Len(t, recordings, 1)
+}
+
+func TestJanitorQuota(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-janitor")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 1, 10, 12, 0, 0, 0, time.Local)
+	write(t, dir, "a", now.Add(-30*time.Minute), 10)
+	write(t, dir, "b", now.Add(-20*time.Minute), 10)
+	write(t, dir, "a", now.Add(-10*time.Minute), 10)
+
+	removed, err := NewJanitor(dir, 0, 25, 0).Clean(now)
+	assert.Nil(t, err)
+	assert.Len(t, removed, 1)
+	assert.Equal(t, "a/2020/01/10/11-30-00.mp4", removed[0].Path)
+
+	removed, err = NewJanitor(dir, 0, 0, 0).Clean(now)
+	assert.Nil(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestJanitorInProgress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-janitor")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 1, 10, 12, 0, 0, 0, time.Local)
+	write(t, dir, "a", now.Add(-20*time.Minute), 10)
+	write(t, dir, "a", now.Add(-10*time.Minute), 10)
+	// the latest segment is still being written
+	assert.Nil(t, os.Chtimes(filepath.Join(dir, "a", "2020", "01", "10", "11-50-00.mp4"), now, now))
+
+	removed, err := NewJanitor(dir, 0, 5, 10*time.Minute).Clean(now)
+	assert.Nil(t, err)
+	assert.Len(t, removed, 1)
+	assert.Equal(t, "a/2020/01/10/11-40-00.mp4", removed[0].Path)
+
+	recordings, err := List(dir)
+	assert.Nil(t, err)
+	assert.Len(t, recordings, 1)
+}
+
+func TestDisabledJanitor(t *testing.T) {
+	removed, err := (*Janitor)(nil).Clean(time.Now())
+	assert.Nil(t, err)
+	assert.Empty(t, removed)
+}
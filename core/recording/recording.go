@@ -0,0 +1,86 @@
+package recording
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileFormat is the strftime format of recording files inside the directory of a stream
+const FileFormat = "%Y/%m/%d/%H-%M-%S.mp4"
+
+// fileLayout is FileFormat in the layout of the time package without the extension
+const fileLayout = "2006/01/02/15-04-05"
+
+// extension is the extension of recording files
+const extension = ".mp4"
+
+// dayLayout is the layout of the dated directories holding the recordings of a day
+const dayLayout = "2006/01/02"
+
+// Recording describes a single recorded file
+type Recording struct {
+	ID       string    // ID of the recorded stream
+	Path     string    // Path of the file relative to the recording directory, with forward slashes
+	Start    time.Time // Time the recording started
+	Size     int64     // Size of the file in bytes
+	Modified time.Time // Time the file was last written
+}
+
+// Prepare creates the dated directories of today and tomorrow in the recording directory of a stream.
+// ffmpeg does not create directories for the files it writes, so they have to exist before the day starts.
+func Prepare(dir string, now time.Time) error {
+	for _, day := range []time.Time{now, now.AddDate(0, 0, 1)} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(day.Format(dayLayout))), os.ModePerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns every recording found in the given directory ordered by their start
+func List(dir string) ([]Recording, error) {
+	recordings := []Recording{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		parts := strings.SplitN(rel, "/", 2)
+		if len(parts) != 2 || !strings.HasSuffix(parts[1], extension) {
+			return nil
+		}
+		start, err := time.ParseInLocation(fileLayout, strings.TrimSuffix(parts[1], extension), time.Local)
+		if err != nil {
+			// not written by the recorder
+			return nil
+		}
+		recordings = append(recordings, Recording{
+			ID:       parts[0],
+			Path:     rel,
+			Start:    start,
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(recordings, func(i, j int) bool {
+		return recordings[i].Start.Before(recordings[j].Start)
+	})
+	return recordings, nil
+}
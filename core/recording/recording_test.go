@@ -0,0 +1,54 @@
+package recording
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// write creates a recording file of the given size
+func write(t *testing.T, dir string, id string, start time.Time, size int) {
+	path := filepath.Join(dir, id, filepath.FromSlash(start.Format(fileLayout)+extension))
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	assert.Nil(t, ioutil.WriteFile(path, make([]byte, size), 0644))
+	assert.Nil(t, os.Chtimes(path, start, start))
+}
+
+func TestPrepare(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-recording")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, Prepare(filepath.Join(dir, "id"), time.Date(2020, 12, 31, 23, 0, 0, 0, time.Local)))
+	for _, day := range []string{"2020/12/31", "2021/01/01"} {
+		info, err := os.Stat(filepath.Join(dir, "id", filepath.FromSlash(day)))
+		assert.Nil(t, err)
+		assert.True(t, info.IsDir())
+	}
+}
+
+func TestList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-recording")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	recordings, err := List(filepath.Join(dir, "missing"))
+	assert.Nil(t, err)
+	assert.Empty(t, recordings)
+
+	first := time.Date(2020, 1, 2, 10, 0, 0, 0, time.Local)
+	write(t, dir, "b", first.Add(10*time.Minute), 2)
+	write(t, dir, "a", first, 1)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "a", "2020", "01", "02", "notes.txt"), nil, 0644))
+
+	recordings, err = List(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []Recording{
+		{ID: "a", Path: "a/2020/01/02/10-00-00.mp4", Start: first, Size: 1, Modified: first},
+		{ID: "b", Path: "b/2020/01/02/10-10-00.mp4", Start: first.Add(10 * time.Minute), Size: 2, Modified: first.Add(10 * time.Minute)},
+	}, recordings)
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/riltech/streamer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListRecordings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-recordings")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	for _, file := range []string{"id/2020/01/02/10-00-00.mp4", "other/2020/01/02/10-00-00.mp4"} {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.Nil(t, ioutil.WriteFile(path, []byte("mp4"), 0644))
+	}
+
+	c := newTestController()
+	c.spec.RecordDir = dir
+	c.registry.Add(&streamer.Stream{ID: "id", OriginalURI: "rtsp://host/1"}, "camera")
+
+	list := func(uri string) []RecordingDTO {
+		w := httptest.NewRecorder()
+		c.ListRecordingsHandler(w, httptest.NewRequest(http.MethodGet, uri, nil), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		dto := []RecordingDTO{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &dto))
+		return dto
+	}
+	assert.Len(t, list("/recordings"), 2)
+
+	dto := list("/recordings?id=camera")
+	assert.Len(t, dto, 1)
+	assert.Equal(t, "id", dto[0].ID)
+	assert.Equal(t, "camera", dto[0].Alias)
+	assert.Equal(t, "/recordings/id/2020/01/02/10-00-00.mp4", dto[0].URI)
+	assert.Equal(t, int64(3), dto[0].Size)
+
+	assert.Empty(t, list("/recordings?id=missing"))
+}
+
+func TestPrepareRecordings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-recordings")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	c := newTestController()
+	c.spec.RecordDir = dir
+	c.registry.Add(newIdleStream("recorded", "rtsp://host/1"), "").Add(newIdleStream("plain", "rtsp://host/2"), "")
+	c.registry.SetOptions("recorded", config.StreamOptions{Record: true})
+	c.prepareRecordings()
+
+	_, err = os.Stat(filepath.Join(dir, "recorded", filepath.FromSlash(time.Now().Format("2006/01/02"))))
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, "plain"))
+	assert.True(t, os.IsNotExist(err))
+}
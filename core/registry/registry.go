@@ -0,0 +1,215 @@
+package registry
+
+import (
+	"sync"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/riltech/streamer"
+)
+
+// IRegistry describes the user panel of the stream registry
+type IRegistry interface {
+	Get(id string) (*streamer.Stream, bool)
+	GetByURI(uri string) (*streamer.Stream, bool)
+	Add(stream *streamer.Stream, alias string) IRegistry
+	Remove(id string) IRegistry
+	Streams() map[string]*streamer.Stream
+	Options(id string) config.StreamOptions
+	SetOptions(id string, opts config.StreamOptions) IRegistry
+	ResolveAlias(alias string) (string, bool)
+	AliasOf(id string) string
+	Preload(alias string) (config.ListenSetting, bool)
+	SetPreload(setting config.ListenSetting) IRegistry
+	RemovePreload(alias string) IRegistry
+	Preloads() map[string]config.ListenSetting
+	Start(uri string, fn func() *streamer.Stream) *streamer.Stream
+}
+
+// call is an in-flight or completed start of a given URI
+type call struct {
+	wg     sync.WaitGroup
+	stream *streamer.Stream
+}
+
+// Registry implements IRegistry
+type Registry struct {
+	mu       sync.RWMutex
+	streams  map[string]*streamer.Stream
+	options  map[string]config.StreamOptions
+	index    map[string]string
+	alias    map[string]string
+	preload  map[string]config.ListenSetting
+	flightMu sync.Mutex
+	flight   map[string]*call
+}
+
+// Type check
+var _ IRegistry = (*Registry)(nil)
+
+// NewRegistry creates a new Registry instance
+func NewRegistry() *Registry {
+	return &Registry{
+		streams: map[string]*streamer.Stream{},
+		options: map[string]config.StreamOptions{},
+		index:   map[string]string{},
+		alias:   map[string]string{},
+		preload: map[string]config.ListenSetting{},
+		flight:  map[string]*call{},
+	}
+}
+
+// Get returns the stream stored under the given ID
+func (r *Registry) Get(id string) (*streamer.Stream, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stream, ok := r.streams[id]
+	return stream, ok
+}
+
+// GetByURI returns the stream which transcodes the given URI
+func (r *Registry) GetByURI(uri string) (*streamer.Stream, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.index[uri]
+	if !ok {
+		return nil, false
+	}
+	stream, ok := r.streams[id]
+	return stream, ok
+}
+
+// Add stores the stream and registers the alias for it if provided
+func (r *Registry) Add(stream *streamer.Stream, alias string) IRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.streams[stream.ID] = stream
+	r.index[stream.OriginalURI] = stream.ID
+	if len(alias) > 0 {
+		r.alias[alias] = stream.ID
+	}
+	return r
+}
+
+// Remove deletes the stream and every alias pointing to it
+func (r *Registry) Remove(id string) IRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stream, ok := r.streams[id]
+	if !ok {
+		return r
+	}
+	delete(r.index, stream.OriginalURI)
+	delete(r.streams, id)
+	delete(r.options, id)
+	for name, target := range r.alias {
+		if target == id {
+			delete(r.alias, name)
+		}
+	}
+	return r
+}
+
+// Streams returns a snapshot of the stored streams keyed by their ID
+func (r *Registry) Streams() map[string]*streamer.Stream {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	streams := make(map[string]*streamer.Stream, len(r.streams))
+	for id, stream := range r.streams {
+		streams[id] = stream
+	}
+	return streams
+}
+
+// Options returns the options the stream was requested with
+func (r *Registry) Options(id string) config.StreamOptions {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.options[id]
+}
+
+// SetOptions stores the options the stream was requested with
+func (r *Registry) SetOptions(id string, opts config.StreamOptions) IRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.options[id] = opts
+	return r
+}
+
+// ResolveAlias returns the ID of the stream the alias points to
+func (r *Registry) ResolveAlias(alias string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.alias[alias]
+	return id, ok
+}
+
+// AliasOf returns the alias registered for the given stream ID or an empty string
+func (r *Registry) AliasOf(id string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name, target := range r.alias {
+		if target == id {
+			return name
+		}
+	}
+	return ""
+}
+
+// Preload returns the setting registered for preloading under the given alias
+func (r *Registry) Preload(alias string) (config.ListenSetting, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	setting, ok := r.preload[alias]
+	return setting, ok
+}
+
+// SetPreload registers a stream to be started lazily under the alias of the setting
+func (r *Registry) SetPreload(setting config.ListenSetting) IRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.preload[setting.Alias] = setting
+	return r
+}
+
+// RemovePreload removes the preload registered under the given alias
+func (r *Registry) RemovePreload(alias string) IRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.preload, alias)
+	return r
+}
+
+// Preloads returns a snapshot of the registered preloads keyed by their alias
+func (r *Registry) Preloads() map[string]config.ListenSetting {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	preloads := make(map[string]config.ListenSetting, len(r.preload))
+	for alias, setting := range r.preload {
+		preloads[alias] = setting
+	}
+	return preloads
+}
+
+// Start makes sure fn runs only once at a time for the given URI.
+// Callers arriving while a start is in flight wait for it and share its result.
+func (r *Registry) Start(uri string, fn func() *streamer.Stream) *streamer.Stream {
+	r.flightMu.Lock()
+	if c, ok := r.flight[uri]; ok {
+		r.flightMu.Unlock()
+		c.wg.Wait()
+		return c.stream
+	}
+	c := &call{}
+	c.wg.Add(1)
+	r.flight[uri] = c
+	r.flightMu.Unlock()
+
+	defer func() {
+		c.wg.Done()
+		r.flightMu.Lock()
+		delete(r.flight, uri)
+		r.flightMu.Unlock()
+	}()
+	c.stream = fn()
+	return c.stream
+}
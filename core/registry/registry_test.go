@@ -0,0 +1,104 @@
+package registry
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/riltech/streamer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Add(&streamer.Stream{ID: "id", OriginalURI: "rtsp://host/1"}, "camera")
+
+	stream, ok := r.Get("id")
+	assert.True(t, ok)
+	assert.Equal(t, "rtsp://host/1", stream.OriginalURI)
+	stream, ok = r.GetByURI("rtsp://host/1")
+	assert.True(t, ok)
+	assert.Equal(t, "id", stream.ID)
+	id, ok := r.ResolveAlias("camera")
+	assert.True(t, ok)
+	assert.Equal(t, "id", id)
+	assert.Equal(t, "camera", r.AliasOf("id"))
+	r.SetOptions("id", config.StreamOptions{Profile: "mobile"})
+	assert.Equal(t, "mobile", r.Options("id").Profile)
+
+	r.Remove("id")
+	assert.Equal(t, config.StreamOptions{}, r.Options("id"))
+	_, ok = r.Get("id")
+	assert.False(t, ok)
+	_, ok = r.GetByURI("rtsp://host/1")
+	assert.False(t, ok)
+	_, ok = r.ResolveAlias("camera")
+	assert.False(t, ok)
+}
+
+func TestPreload(t *testing.T) {
+	r := NewRegistry()
+	setting := config.ListenSetting{Enabled: true, Uri: "rtsp://host/1", Alias: "camera"}
+	r.SetPreload(setting)
+	stored, ok := r.Preload("camera")
+	assert.True(t, ok)
+	assert.Equal(t, setting, stored)
+	assert.Equal(t, map[string]config.ListenSetting{"camera": setting}, r.Preloads())
+	r.RemovePreload("camera")
+	_, ok = r.Preload("camera")
+	assert.False(t, ok)
+}
+
+func TestStartSingleFlight(t *testing.T) {
+	r := NewRegistry()
+	var calls int32
+	start := func() *streamer.Stream {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		stream := &streamer.Stream{ID: "id", OriginalURI: "rtsp://host/1"}
+		r.Add(stream, "")
+		return stream
+	}
+
+	wg := sync.WaitGroup{}
+	results := make([]*streamer.Stream, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			results[n] = r.Start("rtsp://host/1", start)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, stream := range results {
+		assert.Equal(t, results[0], stream)
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	r := NewRegistry()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(3)
+		go func(n int) {
+			defer wg.Done()
+			r.Add(&streamer.Stream{ID: fmt.Sprint(n), OriginalURI: fmt.Sprintf("rtsp://host/%d", n)}, fmt.Sprintf("cam%d", n))
+		}(i)
+		go func(n int) {
+			defer wg.Done()
+			r.Streams()
+			r.AliasOf(fmt.Sprint(n))
+			r.ResolveAlias(fmt.Sprintf("cam%d", n))
+		}(i)
+		go func(n int) {
+			defer wg.Done()
+			r.Remove(fmt.Sprint(n - 1))
+		}(i)
+	}
+	wg.Wait()
+}
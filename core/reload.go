@@ -0,0 +1,54 @@
+package core
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+)
+
+// endpoint returns the current setting of the endpoint with the given name
+func (c *Controller) endpoint(name string) config.EndpointSetting {
+	endpoints, _ := c.endpoints.Load().(config.Endpoints)
+	setting, _ := endpoints.Setting(name)
+	return setting
+}
+
+// Enabled wraps the given handle to respond with not found while the endpoint is disabled.
+// Routes are registered for every endpoint, so reloading the configuration can turn them on and off.
+func (c *Controller) Enabled(endpoint string, handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !c.endpoint(endpoint).Enabled {
+			http.NotFound(w, r)
+			return
+		}
+		handle(w, r, ps)
+	}
+}
+
+// Reload applies the endpoint settings and listen entries of a changed yml configuration.
+// Streams already known by the application are left untouched, only their lazy start definitions change.
+// Profiles and webhooks are only read on startup, changing them is logged as it needs a restart.
+func (c *Controller) Reload(yml config.EndpointYML) {
+	if changed(c.spec.Profiles, yml.Profiles) {
+		logrus.Warnln("profiles changed, restart the application to apply them | Reload")
+	}
+	if changed(c.spec.Webhooks, yml.Webhooks) {
+		logrus.Warnln("webhooks changed, restart the application to apply them | Reload")
+	}
+	c.endpoints.Store(yml.Endpoints)
+	c.listenMu.Lock()
+	defer c.listenMu.Unlock()
+	c.ymlListen = yml.Listen
+	c.syncPreloads()
+}
+
+// changed reports if the yml value differs from the one read on startup, empty values are equal
+func changed(current interface{}, reloaded interface{}) bool {
+	if reflect.ValueOf(current).Len() == 0 && reflect.ValueOf(reloaded).Len() == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(current, reloaded)
+}
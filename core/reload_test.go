@@ -0,0 +1,68 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/julienschmidt/httprouter"
+	"github.com/riltech/streamer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnabled(t *testing.T) {
+	c := newTestController()
+	handle := c.Enabled("list", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	w := httptest.NewRecorder()
+	handle(w, httptest.NewRequest(http.MethodGet, "/list", nil), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c.Reload(config.EndpointYML{Endpoints: config.Endpoints{List: config.EndpointSetting{Enabled: true}}})
+	w = httptest.NewRecorder()
+	handle(w, httptest.NewRequest(http.MethodGet, "/list", nil), nil)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
+
+func TestReload(t *testing.T) {
+	c := newTestController()
+	c.registry.Add(&streamer.Stream{ID: "id", OriginalURI: "rtsp://host/running"}, "running")
+	c.ymlListen = []config.ListenSetting{
+		{Enabled: true, Uri: "rtsp://host/old", Alias: "old"},
+		{Enabled: true, Uri: "rtsp://host/kept", Alias: "kept"},
+	}
+	c.listen["api"] = config.ListenSetting{Enabled: true, Uri: "rtsp://host/api", Alias: "api"}
+	c.syncPreloads()
+	assert.Len(t, c.registry.Preloads(), 3)
+
+	c.Reload(config.EndpointYML{
+		Endpoints: config.Endpoints{Start: config.EndpointSetting{Enabled: true, Secret: "secret"}},
+		Listen: []config.ListenSetting{
+			{Enabled: true, Uri: "rtsp://host/kept", Alias: "kept"},
+			{Enabled: true, Uri: "rtsp://host/new", Alias: "new"},
+			{Enabled: false, Uri: "rtsp://host/disabled", Alias: "disabled"},
+			{Enabled: true, Uri: "rtsp://host/api", Alias: "api", StreamOptions: config.StreamOptions{Record: true}},
+			{Enabled: true, Uri: "rtsp://host/unknown", Alias: "unknown", StreamOptions: config.StreamOptions{Profile: "unknown"}},
+		},
+	})
+	preloads := c.registry.Preloads()
+	assert.Len(t, preloads, 3)
+	assert.Contains(t, preloads, "kept")
+	assert.Contains(t, preloads, "new")
+	// entries registered via the API win over the yml file
+	assert.False(t, preloads["api"].Record)
+	_, ok := c.registry.GetByURI("rtsp://host/running")
+	assert.True(t, ok)
+	assert.Equal(t, "secret", c.endpoint("start").Secret)
+	assert.True(t, c.endpoint("start").Enabled)
+	assert.False(t, c.endpoint("list").Enabled)
+}
+
+func TestChanged(t *testing.T) {
+	assert.False(t, changed(map[string]config.Profile(nil), map[string]config.Profile{}))
+	assert.False(t, changed(map[string]config.Profile{"hd": {Width: 1280}}, map[string]config.Profile{"hd": {Width: 1280}}))
+	assert.True(t, changed(map[string]config.Profile{"hd": {Width: 1280}}, map[string]config.Profile{"hd": {Width: 1920}}))
+	assert.True(t, changed([]config.Webhook(nil), []config.Webhook{{URL: "http://host/hook"}}))
+}
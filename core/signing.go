@@ -0,0 +1,186 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/auth"
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/dash"
+	"github.com/Roverr/rtsp-stream/core/hls"
+	"github.com/julienschmidt/httprouter"
+)
+
+// withQuery appends the query parameters to the URI
+func withQuery(uri string, query url.Values) string {
+	if uri == "" || len(query) == 0 {
+		return uri
+	}
+	separator := "?"
+	if strings.Contains(uri, "?") {
+		separator = "&"
+	}
+	return uri + separator + query.Encode()
+}
+
+// signature returns the query parameters of the signature carried by the request
+func signature(r *http.Request) url.Values {
+	query := r.URL.Query()
+	return url.Values{
+		auth.ExpiresParam:   {query.Get(auth.ExpiresParam)},
+		auth.SignatureParam: {query.Get(auth.SignatureParam)},
+	}
+}
+
+// withoutCredentials returns the query parameters without the token and the signature
+func withoutCredentials(query url.Values) url.Values {
+	rest := url.Values{}
+	for key, values := range query {
+		switch key {
+		case TokenParam, auth.ExpiresParam, auth.SignatureParam:
+		default:
+			rest[key] = values
+		}
+	}
+	return rest
+}
+
+// verifySignature checks the signature of the request for the stream referenced by its ID or alias.
+// Returns false without error if the request is not signed or signing is disabled,
+// so it is authenticated by its token instead.
+func (c *Controller) verifySignature(r *http.Request, ref string) (bool, error) {
+	if c.signer.Expiry() <= 0 || !auth.Signed(r.URL.Query()) {
+		return false, nil
+	}
+	if err := c.signer.Verify(ref, r.URL.Query()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// signDTO signs the playback URLs of the stream for the given time, nothing happens if signing is disabled
+func (c *Controller) signDTO(dto *SummariseDTO, name string, expiry time.Duration) {
+	if c.signer.Expiry() <= 0 {
+		return
+	}
+	expires := time.Now().Add(expiry).Truncate(time.Second)
+	query := c.signer.Sign(name, expires)
+	dto.URI = withQuery(dto.URI, query)
+	dto.MasterURI = withQuery(dto.MasterURI, query)
+	dto.DVRURI = withQuery(dto.DVRURI, query)
+	dto.DashURI = withQuery(dto.DashURI, query)
+	dto.Expires = &expires
+}
+
+// manifestRewriters rewrite the URIs listed in the playlists and manifests by their extension
+var manifestRewriters = map[string]func(data []byte, rewrite func(uri string) string) []byte{
+	".m3u8": hls.RewriteURIs,
+	".mpd":  dash.RewriteURIs,
+}
+
+// playlistRewriter buffers playlists and MPEG-DASH manifests to append the query parameters
+// authenticating the request, the signature or the token, to every URI listed in them
+type playlistRewriter struct {
+	http.ResponseWriter
+	query   url.Values
+	rewrite func(data []byte, rewrite func(uri string) string) []byte
+	status  int
+	body    bytes.Buffer
+}
+
+// WriteHeader holds the status code back until the playlist is signed
+func (p *playlistRewriter) WriteHeader(status int) {
+	if p.status == 0 {
+		p.status = status
+	}
+}
+
+// Write buffers the playlist
+func (p *playlistRewriter) Write(b []byte) (int, error) {
+	p.WriteHeader(http.StatusOK)
+	return p.body.Write(b)
+}
+
+// flush writes out the buffered response, rewriting the playlist if it was served successfully
+func (p *playlistRewriter) flush() {
+	p.WriteHeader(http.StatusOK)
+	body := p.body.Bytes()
+	if p.status == http.StatusOK {
+		body = p.rewrite(body, func(uri string) string {
+			if strings.Contains(uri, "://") {
+				return uri
+			}
+			return withQuery(uri, p.query)
+		})
+		p.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	p.ResponseWriter.WriteHeader(p.status)
+	p.ResponseWriter.Write(body)
+}
+
+// SignHandler is the HTTP handler of the GET /sign/{id} call.
+// Returns playback URLs of the stream which can be used without token until they expire.
+func (c *Controller) SignHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !c.isAuthenticated(r, "sign") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if c.signer.Expiry() <= 0 {
+		c.sendError(w, auth.ErrSigningDisabled, http.StatusNotFound)
+		return
+	}
+	name := ps.ByName("id")
+	if !c.canAccessRef(r, "sign", name) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	expiry := c.signer.Expiry()
+	if value := r.URL.Query().Get("expiry"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			c.sendError(w, fmt.Errorf("Invalid expiry: %s", value), http.StatusBadRequest)
+			return
+		}
+		if d < expiry {
+			expiry = d
+		}
+	}
+
+	dto := SummariseDTO{URI: fmt.Sprintf("/stream/%s/index.m3u8", name)}
+	var opts config.StreamOptions
+	id, ok := c.registry.ResolveAlias(name)
+	if !ok {
+		id = name
+	}
+	if stream, ok := c.registry.Get(id); ok {
+		opts = c.registry.Options(id)
+		dto.Running = stream.Running
+		dto.ID = id
+		dto.Alias = c.registry.AliasOf(id)
+	} else if setting, ok := c.registry.Preload(name); ok {
+		opts = setting.StreamOptions
+		dto.Alias = name
+	} else {
+		c.sendError(w, errors.New("Unknown stream"), http.StatusNotFound)
+		return
+	}
+	dto.MasterURI = c.masterURI(name, opts)
+	dto.DVRURI = c.dvrURI(name, opts)
+	dto.DashURI = c.dashURI(name, opts)
+	c.signDTO(&dto, name, expiry)
+
+	b, err := json.Marshal(dto)
+	if err != nil {
+		c.sendError(w, ErrUnexpected, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(b)
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/auth"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedPlayback(t *testing.T) {
+	storeDir, err := ioutil.TempDir("", "signing")
+	assert.Nil(t, err)
+	defer os.RemoveAll(storeDir)
+	assert.Nil(t, os.MkdirAll(filepath.Join(storeDir, "id"), os.ModePerm))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(storeDir, "id", "index.m3u8"), []byte("#EXTM3U\n#EXTINF:2.000000,\n0.ts\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(storeDir, "id", "0.ts"), []byte("segment"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(storeDir, "id", "manifest.mpd"), []byte(`<SegmentTemplate initialization="init-$RepresentationID$.m4s" media="chunk-$RepresentationID$-$Number%05d$.m4s"/>`), 0644))
+
+	c := newAuthController(t)
+	c.signer = auth.NewSigner("signing", time.Hour)
+	c.fileServer = http.FileServer(http.Dir(storeDir))
+	c.registry.Add(newIdleStream("id", "rtsp://host/1"), "camera").Add(newIdleStream("other", "rtsp://host/2"), "")
+	serve := func(uri string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		file := strings.TrimPrefix(strings.SplitN(uri, "?", 2)[0], "/stream")
+		c.StaticFileHandler(w, httptest.NewRequest(http.MethodGet, uri, nil), httprouter.Params{{Key: "filepath", Value: file}})
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	c.SignHandler(w, newTokenRequest(t, http.MethodGet, "/sign/camera?expiry=10m", "", jwt.MapClaims{"streams": "camera"}), httprouter.Params{{Key: "id", Value: "camera"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	dto := SummariseDTO{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &dto))
+	assert.True(t, dto.Expires.Before(time.Now().Add(11*time.Minute)))
+	assert.True(t, strings.HasPrefix(dto.URI, "/stream/camera/index.m3u8?expires="))
+
+	// the alias is redirected with a signature of the ID
+	w = serve(dto.URI)
+	assert.Equal(t, http.StatusFound, w.Code)
+	location := w.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(location, "/stream/id/index.m3u8?expires="))
+
+	w = serve(location)
+	assert.Equal(t, http.StatusOK, w.Code)
+	segment := strings.Split(strings.TrimSpace(w.Body.String()), "\n")[2]
+	assert.Equal(t, "0.ts?"+strings.SplitN(location, "?", 2)[1], segment)
+	assert.Equal(t, "segment", serve("/stream/id/"+segment).Body.String())
+
+	// segments of MPEG-DASH manifests are signed the same way
+	query := strings.Replace(strings.SplitN(location, "?", 2)[1], "&", "&amp;", -1)
+	w = serve("/stream/id/manifest.mpd?" + strings.SplitN(location, "?", 2)[1])
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `<SegmentTemplate initialization="init-$RepresentationID$.m4s?`+query+`" media="chunk-$RepresentationID$-$Number%05d$.m4s?`+query+`"/>`, w.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, serve("/stream/id/0.ts").Code)
+	assert.Equal(t, http.StatusForbidden, serve("/stream/other/0.ts?"+strings.SplitN(location, "?", 2)[1]).Code)
+	expired := c.signer.Sign("id", time.Now().Add(-time.Second)).Encode()
+	assert.Equal(t, http.StatusForbidden, serve("/stream/id/0.ts?"+expired).Code)
+
+	// the token has to allow the stream to sign it
+	w = httptest.NewRecorder()
+	c.SignHandler(w, newTokenRequest(t, http.MethodGet, "/sign/other", "", jwt.MapClaims{"streams": "camera"}), httprouter.Params{{Key: "id", Value: "other"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = httptest.NewRecorder()
+	c.SignHandler(w, newTokenRequest(t, http.MethodGet, "/sign/unknown", "", nil), httprouter.Params{{Key: "id", Value: "unknown"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSignatureWithoutSigning(t *testing.T) {
+	c := newAuthController(t)
+	c.registry.Add(newIdleStream("id", "rtsp://host/1"), "")
+	query := auth.NewSigner("signing", time.Hour).Sign("id", time.Now().Add(time.Minute)).Encode()
+
+	// the token still authenticates requests carrying a signature
+	w := httptest.NewRecorder()
+	c.StaticFileHandler(w, newTokenRequest(t, http.MethodGet, "/stream/id/0.ts?"+query, "", nil), httprouter.Params{{Key: "filepath", Value: "/id/0.ts"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	c.StaticFileHandler(w, httptest.NewRequest(http.MethodGet, "/stream/id/0.ts?"+query, nil), httprouter.Params{{Key: "filepath", Value: "/id/0.ts"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+)
+
+// SnapshotHandler is the HTTP handler of the GET /snapshot/{id}.jpg call.
+// Aliases are redirected to the ID of the stream, so every name shares the same cached image.
+func (c *Controller) SnapshotHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !c.isAuthenticated(r, "snapshot") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	file := ps.ByName("file")
+	if !strings.HasSuffix(file, ".jpg") {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimSuffix(file, ".jpg")
+	if !c.canAccessRef(r, "snapshot", name) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if id, ok := c.registry.ResolveAlias(name); ok {
+		url := fmt.Sprintf("/snapshot/%s.jpg", id)
+		logrus.Infoln("redirecting alias " + name + " to " + url)
+		http.Redirect(w, r, withQuery(url, c.queryToken(r, "snapshot")), http.StatusFound)
+		return
+	}
+	stream, ok := c.registry.Get(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	stream.Mux.Lock()
+	running := stream.Running
+	stream.Mux.Unlock()
+	if !running {
+		c.sendError(w, fmt.Errorf("%s is not running", name), http.StatusNotFound)
+		return
+	}
+	snap, err := c.snapshots.Get(name, stream.StorePath)
+	if err != nil {
+		logrus.Errorf("Could not take snapshot of %s: %s | Snapshot", name, err)
+		c.sendError(w, err, http.StatusServiceUnavailable)
+		return
+	}
+	// clients can keep the image until the next one is taken
+	fresh := c.snapshots.Interval() - time.Since(snap.Taken)
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(math.Max(0, fresh.Seconds()))))
+	w.Header().Set("Content-Type", "image/jpeg")
+	http.ServeContent(w, r, file, snap.Taken, bytes.NewReader(snap.Image))
+}
@@ -0,0 +1,136 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/hls"
+)
+
+// ErrNoSegment describes an error related to a stream not having any segments written yet
+var ErrNoSegment = errors.New("No segment to take snapshot from")
+
+// Snapshot describes a still image of a stream
+type Snapshot struct {
+	Image []byte    // JPEG encoded image
+	Taken time.Time // Time the image was extracted
+}
+
+// ICache describes the user panel of the snapshots
+type ICache interface {
+	Get(id string, dir string) (*Snapshot, error)
+	Remove(id string)
+	Interval() time.Duration
+}
+
+// entry holds the snapshot of a single stream, its lock makes
+// concurrent requests of the same stream wait for one extraction
+type entry struct {
+	mu       *sync.Mutex
+	snapshot *Snapshot
+}
+
+// Cache implements ICache by extracting snapshots on demand
+// and serving them until the refresh interval passes
+type Cache struct {
+	mu       *sync.Mutex
+	interval time.Duration
+	entries  map[string]*entry
+	extract  func(dir string) ([]byte, error)
+}
+
+// Type check
+var _ ICache = (*Cache)(nil)
+
+// NewCache creates a new Cache refreshing the snapshots after the given interval
+func NewCache(interval time.Duration) *Cache {
+	return &Cache{
+		mu:       &sync.Mutex{},
+		interval: interval,
+		entries:  map[string]*entry{},
+		extract:  Extract,
+	}
+}
+
+// Interval returns the time snapshots are served for before they are refreshed
+func (c *Cache) Interval() time.Duration {
+	return c.interval
+}
+
+// Get returns the snapshot of the stream transcoded into dir, taking a new one if the cached one is too old
+func (c *Cache) Get(id string, dir string) (*Snapshot, error) {
+	c.mu.Lock()
+	e, ok := c.entries[id]
+	if !ok {
+		e = &entry{mu: &sync.Mutex{}}
+		c.entries[id] = e
+	}
+	c.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.snapshot != nil && time.Since(e.snapshot.Taken) < c.interval {
+		return e.snapshot, nil
+	}
+	image, err := c.extract(dir)
+	if err != nil {
+		return nil, err
+	}
+	e.snapshot = &Snapshot{Image: image, Taken: time.Now()}
+	return e.snapshot, nil
+}
+
+// Remove forgets the snapshot of the stream
+func (c *Cache) Remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, id)
+}
+
+// Extract returns the first keyframe of the newest segment listed in the playlist of dir as a JPEG image
+func Extract(dir string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "index.m3u8"))
+	if err != nil {
+		return nil, ErrNoSegment
+	}
+	playlist, err := hls.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(playlist.Segments) == 0 {
+		return nil, ErrNoSegment
+	}
+	input := filepath.Join(dir, playlist.Segments[len(playlist.Segments)-1].URI)
+	if playlist.Map != "" {
+		// fMP4 segments can only be decoded together with their initialization section
+		input = fmt.Sprintf("concat:%s|%s", filepath.Join(dir, playlist.Map), input)
+	}
+	var out bytes.Buffer
+	cmd := exec.Command(
+		"ffmpeg",
+		"-loglevel",
+		"error",
+		"-skip_frame",
+		"nokey",
+		"-i",
+		input,
+		"-frames:v",
+		"1",
+		"-f",
+		"image2",
+		"-c:v",
+		"mjpeg",
+		"pipe:1",
+	)
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
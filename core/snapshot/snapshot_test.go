@@ -0,0 +1,77 @@
+package snapshot
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	cache := NewCache(time.Minute)
+	mu := &sync.Mutex{}
+	calls := 0
+	cache.extract = func(dir string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return []byte(dir), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			snapshot, err := cache.Get("id", "/videos/id")
+			assert.Nil(t, err)
+			assert.Equal(t, []byte("/videos/id"), snapshot.Image)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, calls)
+
+	_, err := cache.Get("other", "/videos/other")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+
+	// outdated snapshots are taken again
+	cache.entries["id"].snapshot.Taken = time.Now().Add(-2 * time.Minute)
+	_, err = cache.Get("id", "/videos/id")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+
+	// removed streams do not keep their snapshots around
+	cache.Remove("other")
+	assert.NotContains(t, cache.entries, "other")
+	_, err = cache.Get("other", "/videos/other")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, calls)
+}
+
+func TestCacheError(t *testing.T) {
+	cache := NewCache(time.Minute)
+	cache.extract = func(dir string) ([]byte, error) {
+		return nil, errors.New("failed")
+	}
+	_, err := cache.Get("id", "/videos/id")
+	assert.NotNil(t, err)
+}
+
+func TestExtractWithoutSegment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-snapshot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Extract(dir)
+	assert.Equal(t, ErrNoSegment, err)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "index.m3u8"), []byte("#EXTM3U\n#EXT-X-TARGETDURATION:1\n"), 0644))
+	_, err = Extract(dir)
+	assert.Equal(t, ErrNoSegment, err)
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Roverr/rtsp-stream/core/snapshot"
+	"github.com/julienschmidt/httprouter"
+	"github.com/riltech/streamer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotHandler(t *testing.T) {
+	c := newTestController()
+	c.registry.Add(newRunningStream("id", "rtsp://host/1"), "camera")
+	c.registry.Add(&streamer.Stream{ID: "stopped", OriginalURI: "rtsp://host/2", Mux: &sync.Mutex{}}, "")
+	// running streams without segments cannot have snapshots yet
+	stream, _ := c.registry.Get("id")
+	stream.StorePath = "/nonexistent"
+
+	get := func(file string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c.SnapshotHandler(w, httptest.NewRequest(http.MethodGet, "/snapshot/"+file, nil), httprouter.Params{{Key: "file", Value: file}})
+		return w
+	}
+
+	w := get("camera.jpg")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/snapshot/id.jpg", w.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusNotFound, get("id.png").Code)
+	assert.Equal(t, http.StatusNotFound, get("unknown.jpg").Code)
+	assert.Equal(t, http.StatusNotFound, get("stopped.jpg").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("id.jpg").Code)
+}
+
+// removedSnapshots records the streams whose snapshots were removed
+type removedSnapshots struct {
+	snapshot.ICache
+	ids []string
+}
+
+func (r *removedSnapshots) Remove(id string) {
+	r.ids = append(r.ids, id)
+}
+
+func TestSnapshotCleanup(t *testing.T) {
+	c := newTestController()
+	removed := &removedSnapshots{ICache: c.snapshots}
+	c.snapshots = removed
+	c.registry.Add(newIdleStream("idle", "rtsp://host/1"), "").Add(newIdleStream("stopped", "rtsp://host/2"), "")
+
+	c.stopInactiveStreams()
+	assert.ElementsMatch(t, []string{"idle", "stopped"}, removed.ids)
+	removed.ids = nil
+	w := httptest.NewRecorder()
+	c.StopStreamHandler(w, httptest.NewRequest(http.MethodPost, "/stop", strings.NewReader(`{"id":"stopped","remove":true}`)), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"stopped"}, removed.ids)
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+)
+
+// Record describes a stream that is kept between restarts
+type Record struct {
+	ID      string `json:"id"`
+	URI     string `json:"uri"`
+	Alias   string `json:"alias"`
+	Running bool   `json:"running"` // Desired state of the stream
+	config.StreamOptions
+}
+
+// IStore describes the user panel of a persistence layer
+type IStore interface {
+	Load() ([]Record, error)
+	Save(records []Record) error
+	LoadListen() ([]config.ListenSetting, error)
+	SaveListen(settings []config.ListenSetting) error
+}
+
+// FileStore implements IStore by keeping records in JSON files
+type FileStore struct {
+	mu         *sync.Mutex
+	path       string
+	listenPath string
+}
+
+// Type check
+var _ IStore = (*FileStore)(nil)
+
+// NewFileStore creates a new FileStore instance storing its file in the given directory
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &FileStore{
+		mu:         &sync.Mutex{},
+		path:       filepath.Join(dir, "streams.json"),
+		listenPath: filepath.Join(dir, "listen.json"),
+	}, nil
+}
+
+// Load reads every stored record. Missing file means there is nothing stored yet.
+func (fs *FileStore) Load() ([]Record, error) {
+	if fs == nil {
+		return nil, nil
+	}
+	records := []Record{}
+	if err := fs.read(fs.path, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Save replaces the stored records with the given ones
+func (fs *FileStore) Save(records []Record) error {
+	if fs == nil {
+		return nil
+	}
+	return fs.write(fs.path, records)
+}
+
+// LoadListen reads the preloads registered at runtime. Missing file means there is nothing stored yet.
+func (fs *FileStore) LoadListen() ([]config.ListenSetting, error) {
+	if fs == nil {
+		return nil, nil
+	}
+	settings := []config.ListenSetting{}
+	if err := fs.read(fs.listenPath, &settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SaveListen replaces the stored preloads with the given ones
+func (fs *FileStore) SaveListen(settings []config.ListenSetting) error {
+	if fs == nil {
+		return nil
+	}
+	return fs.write(fs.listenPath, settings)
+}
+
+// read decodes the JSON file into v, leaving v untouched if the file does not exist
+func (fs *FileStore) read(path string, v interface{}) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// write replaces the JSON file with the encoding of v.
+// The file is swapped atomically so a crash cannot leave a half written state behind.
+func (fs *FileStore) write(path string, v interface{}) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-store")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fs, err := NewFileStore(dir)
+	assert.Nil(t, err)
+	records, err := fs.Load()
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+
+	expected := []Record{
+		{ID: "id", URI: "rtsp://host/1", Alias: "camera", Running: true},
+		{ID: "id2", URI: "rtsp://host/2"},
+	}
+	assert.Nil(t, fs.Save(expected))
+	records, err = fs.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, records)
+
+	fs, err = NewFileStore(dir)
+	assert.Nil(t, err)
+	records, err = fs.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, records)
+}
+
+func TestListen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-store")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fs, err := NewFileStore(dir)
+	assert.Nil(t, err)
+	settings, err := fs.LoadListen()
+	assert.Nil(t, err)
+	assert.Empty(t, settings)
+
+	expected := []config.ListenSetting{
+		{Enabled: true, Uri: "rtsp://host/1", Alias: "camera", StreamOptions: config.StreamOptions{Profile: "mobile"}},
+	}
+	assert.Nil(t, fs.SaveListen(expected))
+	settings, err = fs.LoadListen()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, settings)
+}
+
+func TestDisabledStore(t *testing.T) {
+	fs := (*FileStore)(nil)
+	assert.Nil(t, fs.Save([]Record{{ID: "id"}}))
+	records, err := fs.Load()
+	assert.Nil(t, err)
+	assert.Nil(t, records)
+	assert.Nil(t, fs.SaveListen([]config.ListenSetting{{Alias: "camera"}}))
+	settings, err := fs.LoadListen()
+	assert.Nil(t, err)
+	assert.Nil(t, settings)
+}
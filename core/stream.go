@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/transcoder"
+	"github.com/riltech/streamer"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	// DASH files are not known by every system, make sure the file server sends proper types
+	mime.AddExtensionType(".mpd", "application/dash+xml")
+	mime.AddExtensionType(".m4s", "video/iso.segment")
+}
+
+// newStream creates a new stream for the given URI without starting it.
+// If id is not empty the stream is bound to it instead of the generated one,
+// so streams restored from the store keep their public paths.
+func (c *Controller) newStream(URI string, id string, opts config.StreamOptions) *streamer.Stream {
+	profile, err := c.spec.Profile(opts.Profile)
+	if err != nil {
+		logrus.Errorf("%s for %s, using default profile | Transcoder", err, URI)
+		profile, _ = c.spec.Profile("")
+	}
+	stream, generated := streamer.NewStream(
+		URI,
+		c.spec.StoreDir,
+		*profile.KeepFiles,
+		*profile.Audio,
+		streamer.ProcessLoggingOpts{
+			Enabled:    c.spec.ProcessLogging.Enabled,
+			Compress:   c.spec.ProcessLogging.Compress,
+			Directory:  c.spec.ProcessLogging.Directory,
+			MaxAge:     c.spec.ProcessLogging.MaxAge,
+			MaxBackups: c.spec.ProcessLogging.MaxBackups,
+			MaxSize:    c.spec.ProcessLogging.MaxSize,
+		},
+		profile.StartupTimeout,
+	)
+	if id != "" && id != generated {
+		if err := os.RemoveAll(stream.StorePath); err != nil {
+			logrus.Error(err)
+		}
+		stream.ID = id
+		stream.StorePath = filepath.Join(c.spec.StoreDir, id)
+		stream.Path = fmt.Sprintf("/stream/%s/index.m3u8", id)
+	}
+	rec := transcoder.Recording{}
+	if opts.Record {
+		rec.Dir = filepath.Join(c.spec.RecordDir, stream.ID)
+		rec.SegmentLength = c.spec.RecordSegmentLength
+	}
+	stream.Process = transcoder.NewProcess(profile, rec)
+	stream.CMD = stream.Process.Spawn(stream.StorePath, URI)
+	return stream
+}
+
+// masterURI returns the path of the master playlist if the stream has adaptive bitrate renditions
+func (c *Controller) masterURI(name string, opts config.StreamOptions) string {
+	profile, err := c.spec.Profile(opts.Profile)
+	if err != nil || len(profile.Renditions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("/stream/%s/%s", name, transcoder.MasterPlaylist)
+}
+
+// dashURI returns the path of the MPEG-DASH manifest if the stream emits one
+func (c *Controller) dashURI(name string, opts config.StreamOptions) string {
+	profile, err := c.spec.Profile(opts.Profile)
+	if err != nil || !*profile.Dash {
+		return ""
+	}
+	return fmt.Sprintf("/stream/%s/%s", name, transcoder.DashManifest)
+}
+
+// dvrURI returns the path of the timeshift playlist if the stream keeps a DVR window
+func (c *Controller) dvrURI(name string, opts config.StreamOptions) string {
+	profile, err := c.spec.Profile(opts.Profile)
+	if err != nil || profile.DVRWindow <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("/stream/%s/%s", name, DVRPlaylist)
+}
@@ -0,0 +1,26 @@
+package supervisor
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff describes how long to wait before restart attempts
+type Backoff struct {
+	Min time.Duration // Wait before the first attempt
+	Max time.Duration // Upper limit of the wait
+}
+
+// Duration returns the wait before the given attempt, counted from zero.
+// The wait doubles with every attempt and is randomised into its upper half,
+// so streams failing together do not restart together.
+func (b Backoff) Duration(attempt int) time.Duration {
+	d := b.Max
+	if attempt < 32 && b.Min<<uint(attempt) > 0 && b.Min<<uint(attempt) < b.Max {
+		d = b.Min << uint(attempt)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
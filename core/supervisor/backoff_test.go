@@ -0,0 +1,23 @@
+package supervisor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	b := Backoff{Min: time.Second, Max: time.Minute}
+	for i := 0; i < 100; i++ {
+		d := b.Duration(0)
+		assert.True(t, d >= 500*time.Millisecond && d <= time.Second, d)
+		d = b.Duration(3)
+		assert.True(t, d >= 4*time.Second && d <= 8*time.Second, d)
+		d = b.Duration(10)
+		assert.True(t, d >= 30*time.Second && d <= time.Minute, d)
+		d = b.Duration(100)
+		assert.True(t, d >= 30*time.Second && d <= time.Minute, d)
+	}
+	assert.Equal(t, time.Duration(0), Backoff{}.Duration(5))
+}
@@ -0,0 +1,240 @@
+package supervisor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/riltech/streamer"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrGiveUp describes an error that stops the supervision of a stream
+var ErrGiveUp = errors.New("Giving up on stream")
+
+// Reasons of restarts
+const (
+	ReasonExited  = "exited"
+	ReasonStalled = "stalled"
+)
+
+// historySize is the number of restarts remembered per stream
+const historySize = 10
+
+// Restart describes a restart attempt of a stream
+type Restart struct {
+	Time    time.Time `json:"time"`
+	Reason  string    `json:"reason"`
+	Attempt int       `json:"attempt"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// RestartFunc brings a failed stream back. Returning ErrGiveUp stops the supervision of the stream.
+// Without one failures are only reported.
+type RestartFunc func(stream *streamer.Stream) error
+
+// ISupervisor describes the user panel of the supervisor
+type ISupervisor interface {
+	Watch(stream *streamer.Stream)
+	Unwatch(id string)
+	Remove(id string)
+	Watching(id string) bool
+	History(id string) []Restart
+}
+
+// Supervisor restarts streams whose transcoding process exited or stopped writing segments
+type Supervisor struct {
+	mu           *sync.RWMutex
+	interval     time.Duration
+	stallTimeout time.Duration
+	backoff      Backoff
+	restart      RestartFunc
+	onFailure    func(stream *streamer.Stream, reason string)
+	watched      map[string]chan struct{}
+	history      map[string][]Restart
+}
+
+// Type check
+var _ ISupervisor = (*Supervisor)(nil)
+
+// NewSupervisor creates a new Supervisor. Streams not updating their playlist
+// for stallTimeout are considered stalled, 0 turns off stall detection.
+func NewSupervisor(stallTimeout time.Duration, backoff Backoff, restart RestartFunc) *Supervisor {
+	return &Supervisor{
+		mu:           &sync.RWMutex{},
+		interval:     time.Second,
+		stallTimeout: stallTimeout,
+		backoff:      backoff,
+		restart:      restart,
+		watched:      map[string]chan struct{}{},
+		history:      map[string][]Restart{},
+	}
+}
+
+// OnFailure sets the function called with the reason whenever a supervised stream fails.
+// It has to be set before streams are supervised.
+func (s *Supervisor) OnFailure(handler func(stream *streamer.Stream, reason string)) *Supervisor {
+	if s == nil {
+		return nil
+	}
+	s.onFailure = handler
+	return s
+}
+
+// Watch starts supervising the stream if it is not supervised yet
+func (s *Supervisor) Watch(stream *streamer.Stream) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.watched[stream.ID]; ok {
+		return
+	}
+	done := make(chan struct{})
+	s.watched[stream.ID] = done
+	go s.supervise(stream, done)
+}
+
+// Unwatch stops supervising the stream, used before stopping it on purpose
+func (s *Supervisor) Unwatch(id string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if done, ok := s.watched[id]; ok {
+		close(done)
+		delete(s.watched, id)
+	}
+}
+
+// Remove stops supervising the stream and forgets its history
+func (s *Supervisor) Remove(id string) {
+	if s == nil {
+		return
+	}
+	s.Unwatch(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.history, id)
+}
+
+// Watching shows if the stream is supervised and restarted after failures
+func (s *Supervisor) Watching(id string) bool {
+	if s == nil || s.restart == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.watched[id]
+	return ok
+}
+
+// History returns the latest restarts of the stream, oldest first
+func (s *Supervisor) History(id string) []Restart {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Restart(nil), s.history[id]...)
+}
+
+// record saves a restart into the history of the stream
+func (s *Supervisor) record(id string, restart Restart) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := append(s.history[id], restart)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	s.history[id] = history
+}
+
+// giveUp stops supervising the stream if it is still supervised by the given run
+func (s *Supervisor) giveUp(id string, done chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.watched[id] == done {
+		delete(s.watched, id)
+	}
+}
+
+// check returns the reason the stream needs a restart, or empty string if it is healthy
+func (s *Supervisor) check(stream *streamer.Stream, started time.Time) string {
+	stream.Mux.Lock()
+	running := stream.Running
+	stream.Mux.Unlock()
+	if !running {
+		return ReasonExited
+	}
+	if s.stallTimeout <= 0 {
+		return ""
+	}
+	updated := started
+	if info, err := os.Stat(filepath.Join(stream.StorePath, "index.m3u8")); err == nil && info.ModTime().After(updated) {
+		updated = info.ModTime()
+	}
+	if time.Since(updated) > s.stallTimeout {
+		return ReasonStalled
+	}
+	return ""
+}
+
+// supervise checks the stream periodically and restarts it with backoff until done is closed
+func (s *Supervisor) supervise(stream *streamer.Stream, done chan struct{}) {
+	attempt := 0
+	started := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(s.interval):
+		}
+		reason := s.check(stream, started)
+		if reason == "" {
+			continue
+		}
+		// streams running well since the last restart start over with the shortest wait
+		if time.Since(started) > s.backoff.Max {
+			attempt = 0
+		}
+		if s.onFailure != nil {
+			s.onFailure(stream, reason)
+		}
+		// the failure is only reported, the stream is watched again once it is started
+		if s.restart == nil {
+			logrus.Infof("%s %s | Supervisor", stream.ID, reason)
+			s.giveUp(stream.ID, done)
+			return
+		}
+		logrus.Infof("%s %s, restarting | Supervisor", stream.ID, reason)
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(s.backoff.Duration(attempt)):
+			}
+			attempt++
+			err := s.restart(stream)
+			restart := Restart{Time: time.Now(), Reason: reason, Attempt: attempt}
+			if err != nil {
+				restart.Error = err.Error()
+			}
+			s.record(stream.ID, restart)
+			if err == nil {
+				started = time.Now()
+				break
+			}
+			if err == ErrGiveUp {
+				logrus.Infof("%s is not supervised anymore | Supervisor", stream.ID)
+				s.giveUp(stream.ID, done)
+				return
+			}
+			logrus.Errorf("%s could not be restarted: %s | Supervisor", stream.ID, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package supervisor
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/riltech/streamer"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSupervisor creates a supervisor checking streams quickly
+func newTestSupervisor(stallTimeout time.Duration, restart RestartFunc) *Supervisor {
+	s := NewSupervisor(stallTimeout, Backoff{Min: time.Millisecond, Max: 4 * time.Millisecond}, restart)
+	s.interval = 5 * time.Millisecond
+	return s
+}
+
+// setRunning changes the state of the stream the way the transcoding process does
+func setRunning(stream *streamer.Stream, running bool) {
+	stream.Mux.Lock()
+	stream.Running = running
+	stream.Mux.Unlock()
+}
+
+// eventually waits for the condition to be met
+func eventually(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRestartExited(t *testing.T) {
+	stream := &streamer.Stream{ID: "id", Running: true, Mux: &sync.Mutex{}}
+	mu := &sync.Mutex{}
+	calls := 0
+	s := newTestSupervisor(0, func(stream *streamer.Stream) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			return errors.New("camera is down")
+		}
+		setRunning(stream, true)
+		return nil
+	})
+	failures := make(chan string, 1)
+	s.OnFailure(func(stream *streamer.Stream, reason string) {
+		failures <- reason
+	})
+	s.Watch(stream)
+	s.Watch(stream)
+	assert.True(t, s.Watching("id"))
+
+	setRunning(stream, false)
+	assert.Equal(t, ReasonExited, <-failures)
+	eventually(t, func() bool { return len(s.History("id")) == 3 })
+	history := s.History("id")
+	assert.Equal(t, "camera is down", history[0].Error)
+	assert.Equal(t, ReasonExited, history[2].Reason)
+	assert.Equal(t, 3, history[2].Attempt)
+	assert.Empty(t, history[2].Error)
+	assert.True(t, s.Watching("id"))
+
+	s.Remove("id")
+	assert.False(t, s.Watching("id"))
+	assert.Empty(t, s.History("id"))
+}
+
+func TestGiveUp(t *testing.T) {
+	stream := &streamer.Stream{ID: "id", Running: false, Mux: &sync.Mutex{}}
+	s := newTestSupervisor(0, func(stream *streamer.Stream) error {
+		return ErrGiveUp
+	})
+	s.Watch(stream)
+	eventually(t, func() bool { return !s.Watching("id") })
+	assert.Len(t, s.History("id"), 1)
+}
+
+func TestReportExited(t *testing.T) {
+	stream := &streamer.Stream{ID: "id", Running: true, Mux: &sync.Mutex{}}
+	s := newTestSupervisor(0, nil)
+	failures := make(chan string, 2)
+	s.OnFailure(func(stream *streamer.Stream, reason string) {
+		failures <- reason
+	})
+	watched := func() bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		_, ok := s.watched["id"]
+		return ok
+	}
+	s.Watch(stream)
+	assert.True(t, watched())
+	assert.False(t, s.Watching("id"))
+
+	setRunning(stream, false)
+	assert.Equal(t, ReasonExited, <-failures)
+	eventually(t, func() bool { return !watched() })
+	assert.Empty(t, s.History("id"))
+	assert.Len(t, failures, 0)
+
+	// streams started again are reported on their next exit
+	setRunning(stream, true)
+	s.Watch(stream)
+	setRunning(stream, false)
+	assert.Equal(t, ReasonExited, <-failures)
+}
+
+func TestUnwatch(t *testing.T) {
+	stream := &streamer.Stream{ID: "id", Running: true, Mux: &sync.Mutex{}}
+	s := newTestSupervisor(0, func(stream *streamer.Stream) error {
+		t.Error("unwatched stream is restarted")
+		return nil
+	})
+	s.Watch(stream)
+	s.Unwatch("id")
+	setRunning(stream, false)
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, s.Watching("id"))
+}
+
+func TestRestartStalled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rtsp-stream-supervisor")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	playlist := filepath.Join(dir, "index.m3u8")
+	assert.Nil(t, ioutil.WriteFile(playlist, nil, 0644))
+
+	stream := &streamer.Stream{ID: "id", Running: true, Mux: &sync.Mutex{}, StorePath: dir}
+	s := newTestSupervisor(50*time.Millisecond, func(stream *streamer.Stream) error {
+		assert.Nil(t, os.Chtimes(playlist, time.Now(), time.Now()))
+		return nil
+	})
+	s.Watch(stream)
+	eventually(t, func() bool { return len(s.History("id")) > 0 })
+	assert.Equal(t, ReasonStalled, s.History("id")[0].Reason)
+	s.Unwatch("id")
+}
+
+func TestDisabledSupervisor(t *testing.T) {
+	s := (*Supervisor)(nil)
+	s.Watch(&streamer.Stream{ID: "id"})
+	s.Unwatch("id")
+	s.Remove("id")
+	assert.False(t, s.Watching("id"))
+	assert.Empty(t, s.History("id"))
+}
@@ -0,0 +1,70 @@
+package transcoder
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+)
+
+// MasterPlaylist is the name of the generated master playlist of adaptive bitrate streams
+const MasterPlaylist = "master.m3u8"
+
+// defaultBandwidth is advertised for the main output if neither bandwidth nor bitrate is known
+const defaultBandwidth = 5000000
+
+// Master generates the master playlist listing the main output and every rendition of the profile
+func Master(profile config.Profile) []byte {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+	bandwidth := ParseBitrate(profile.Bandwidth)
+	if bandwidth == 0 {
+		bandwidth = ParseBitrate(profile.Bitrate)
+	}
+	if bandwidth == 0 {
+		bandwidth = defaultBandwidth
+	}
+	writeVariant(&b, bandwidth, profile.Width, profile.Height, "index.m3u8")
+	for _, rendition := range profile.Renditions {
+		writeVariant(&b, ParseBitrate(rendition.Bitrate), rendition.Width, rendition.Height, rendition.Name+"/index.m3u8")
+	}
+	return b.Bytes()
+}
+
+// writeVariant writes a single variant stream entry into the playlist.
+// RESOLUTION is only known if both sizes are set, the other side follows the aspect ratio of the camera otherwise.
+// CODECS is left out, the H.264 profile and level are picked by the encoder or come from the camera.
+func writeVariant(b *bytes.Buffer, bandwidth int, width int, height int, uri string) {
+	b.WriteString(fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d", bandwidth))
+	if width > 0 && height > 0 {
+		b.WriteString(fmt.Sprintf(",RESOLUTION=%dx%d", width, height))
+	}
+	b.WriteString("\n" + uri + "\n")
+}
+
+// ParseBitrate converts a bitrate in ffmpeg format like "800k" or "2M" into bits per second.
+// Returns 0 for empty or invalid values.
+func ParseBitrate(bitrate string) int {
+	bitrate = strings.TrimSpace(bitrate)
+	if bitrate == "" {
+		return 0
+	}
+	multiplier := 1.0
+	switch bitrate[len(bitrate)-1] {
+	case 'k', 'K':
+		multiplier = 1000
+	case 'm', 'M':
+		multiplier = 1000000
+	}
+	if multiplier != 1 {
+		bitrate = bitrate[:len(bitrate)-1]
+	}
+	value, err := strconv.ParseFloat(bitrate, 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return int(value * multiplier)
+}
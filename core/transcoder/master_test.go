@@ -0,0 +1,48 @@
+package transcoder
+
+import (
+	"testing"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaster(t *testing.T) {
+	master := Master(config.Profile{
+		Codec: "copy",
+		Renditions: []config.Rendition{
+			{Name: "720p", Width: 1280, Height: 720, Bitrate: "2.5M"},
+			{Name: "360p", Height: 360, Bitrate: "800k"},
+		},
+	})
+	assert.Equal(t, `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-STREAM-INF:BANDWIDTH=5000000
+index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2500000,RESOLUTION=1280x720
+720p/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=800000
+360p/index.m3u8
+`, string(master))
+
+	master = Master(config.Profile{Width: 1920, Height: 1080, Bitrate: "4000k"})
+	assert.Contains(t, string(master), "#EXT-X-STREAM-INF:BANDWIDTH=4000000,RESOLUTION=1920x1080\nindex.m3u8")
+}
+
+func TestParseBitrate(t *testing.T) {
+	tt := []struct {
+		Input  string
+		Output int
+	}{
+		{"", 0},
+		{"800k", 800000},
+		{"800K", 800000},
+		{"2M", 2000000},
+		{"1.5m", 1500000},
+		{"64000", 64000},
+		{"fast", 0},
+	}
+	for _, testCase := range tt {
+		assert.Equal(t, testCase.Output, ParseBitrate(testCase.Input), testCase.Input)
+	}
+}
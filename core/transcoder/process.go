@@ -0,0 +1,241 @@
+package transcoder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/hls"
+	"github.com/Roverr/rtsp-stream/core/recording"
+	"github.com/riltech/streamer"
+	"github.com/sirupsen/logrus"
+)
+
+// DashManifest is the name of the MPEG-DASH manifest of a stream
+const DashManifest = "manifest.mpd"
+
+// Recording describes where the stream is recorded to, empty Dir turns off recording
+type Recording struct {
+	Dir           string        // Recording directory of the stream
+	SegmentLength time.Duration // Length of each recorded file
+}
+
+// Process implements streamer.IProcess by spawning ffmpeg with the settings of a profile
+type Process struct {
+	profile   config.Profile
+	recording Recording
+}
+
+// Type check
+var _ streamer.IProcess = (*Process)(nil)
+
+// NewProcess creates a new Process for the given resolved profile
+func NewProcess(profile config.Profile, recording Recording) *Process {
+	return &Process{profile, recording}
+}
+
+// Spawn creates the ffmpeg command transcoding URI into the given directory
+func (p Process) Spawn(path, URI string) *exec.Cmd {
+	os.MkdirAll(path, os.ModePerm)
+	for _, rendition := range p.profile.Renditions {
+		os.MkdirAll(filepath.Join(path, rendition.Name), os.ModePerm)
+	}
+	if len(p.profile.Renditions) > 0 {
+		if err := ioutil.WriteFile(filepath.Join(path, MasterPlaylist), Master(p.profile), 0644); err != nil {
+			logrus.Errorf("Could not write master playlist: %s | Transcoder", err)
+		}
+	}
+	if p.recording.Dir != "" {
+		if err := recording.Prepare(p.recording.Dir, time.Now()); err != nil {
+			logrus.Errorf("Could not create recording directory: %s | Transcoder", err)
+		}
+	}
+	return exec.Command("ffmpeg", p.Args(path, URI)...)
+}
+
+// Args returns the arguments ffmpeg is called with
+func (p Process) Args(path, URI string) []string {
+	args := []string{
+		"-y",
+		"-fflags",
+		"nobuffer",
+		"-rtsp_transport",
+		"tcp",
+		"-i",
+		URI,
+		"-vsync",
+		"0",
+		"-copyts",
+	}
+	args = append(args, p.videoArgs(p.profile.Codec, p.profile.Width, p.profile.Height, p.profile.Bitrate, p.profile.Framerate)...)
+	args = append(args, p.outputArgs(path)...)
+	for _, rendition := range p.profile.Renditions {
+		args = append(args, p.videoArgs(p.profile.Codec, rendition.Width, rendition.Height, rendition.Bitrate, rendition.Framerate)...)
+		args = append(args, p.outputArgs(filepath.Join(path, rendition.Name))...)
+	}
+	if p.profile.Dash != nil && *p.profile.Dash {
+		// DASH is a separate output of the same input, re-encoded profiles are encoded for it once more
+		args = append(args, p.videoArgs(p.profile.Codec, p.profile.Width, p.profile.Height, p.profile.Bitrate, p.profile.Framerate)...)
+		args = append(args, p.dashArgs(path)...)
+	}
+	if p.recording.Dir != "" {
+		args = append(args, p.videoArgs(p.profile.Codec, p.profile.Width, p.profile.Height, p.profile.Bitrate, p.profile.Framerate)...)
+		args = append(args, p.recordArgs()...)
+	}
+	return args
+}
+
+// recordArgs returns the arguments of the output writing fixed length MP4 files into the dated recording directories
+func (p Process) recordArgs() []string {
+	args := p.audioArgs()
+	return append(args,
+		"-f",
+		"segment",
+		"-segment_time",
+		seconds(p.recording.SegmentLength.Seconds()),
+		"-segment_format",
+		"mp4",
+		"-reset_timestamps",
+		"1",
+		"-strftime",
+		"1",
+		fmt.Sprintf("%s/%s", p.recording.Dir, recording.FileFormat),
+	)
+}
+
+// dashArgs returns the arguments of the MPEG-DASH output written into the given directory
+func (p Process) dashArgs(dir string) []string {
+	args := p.audioArgs()
+	// window size of 0 keeps every segment in the manifest and on the disk
+	window := "0"
+	if p.profile.KeepFiles == nil || !*p.profile.KeepFiles {
+		window = strconv.Itoa(p.profile.ListSize)
+	}
+	return append(args,
+		"-f",
+		"dash",
+		"-seg_duration",
+		seconds(p.profile.SegmentLength.Seconds()),
+		"-window_size",
+		window,
+		"-extra_window_size",
+		"0",
+		"-use_template",
+		"1",
+		"-use_timeline",
+		"1",
+		"-init_seg_name",
+		"init-$RepresentationID$.m4s",
+		"-media_seg_name",
+		"chunk-$RepresentationID$-$Number%05d$.m4s",
+		fmt.Sprintf("%s/%s", dir, DashManifest),
+	)
+}
+
+// outputArgs returns the arguments of an HLS output written into the given directory
+func (p Process) outputArgs(dir string) []string {
+	args := p.audioArgs()
+	flags := "append_list"
+	if p.profile.KeepFiles == nil || !*p.profile.KeepFiles {
+		flags = "delete_segments+append_list"
+	}
+	// segments of the DVR window stay listed, dates are needed to pick them by time
+	listSize := p.profile.ListSize + p.profile.DVRSegments()
+	if p.profile.DVRWindow > 0 {
+		flags += "+program_date_time"
+	}
+	if p.profile.LowLatency {
+		// every segment written by ffmpeg is a partial segment, full segments are assembled on request
+		parts := p.profile.PartsPerSegment()
+		return append(args,
+			"-f",
+			"hls",
+			"-hls_segment_type",
+			"fmp4",
+			"-hls_fmp4_init_filename",
+			"init.mp4",
+			"-hls_flags",
+			flags+"+temp_file",
+			"-hls_time",
+			seconds(p.profile.PartLength.Seconds()),
+			"-hls_list_size",
+			strconv.Itoa((listSize+1)*parts),
+			"-hls_segment_filename",
+			fmt.Sprintf("%s/%s", dir, hls.PartFormat),
+			fmt.Sprintf("%s/index.m3u8", dir),
+		)
+	}
+	return append(args,
+		"-f",
+		"hls",
+		"-hls_flags",
+		flags,
+		"-hls_time",
+		seconds(p.profile.SegmentLength.Seconds()),
+		"-hls_list_size",
+		strconv.Itoa(listSize),
+		"-hls_segment_filename",
+		fmt.Sprintf("%s/%%d.ts", dir),
+		fmt.Sprintf("%s/index.m3u8", dir),
+	)
+}
+
+// audioArgs returns the arguments describing how the audio of an output is encoded
+func (p Process) audioArgs() []string {
+	if p.profile.Audio != nil && *p.profile.Audio {
+		return []string{"-c:a", "aac"}
+	}
+	return []string{"-an"}
+}
+
+// ForcesKeyframes reports if the main output of the profile is re-encoded with keyframes forced
+// at the boundaries of its segments, or of its partial segments in low latency mode
+func ForcesKeyframes(profile config.Profile) bool {
+	return profile.LowLatency || profile.Codec != "copy" ||
+		profile.Width > 0 || profile.Height > 0 || profile.Bitrate != "" || profile.Framerate > 0
+}
+
+// videoArgs returns the arguments describing how the video of an output is encoded.
+// Low latency outputs are always re-encoded, as copied keyframes rarely land on part boundaries.
+func (p Process) videoArgs(codec string, width int, height int, bitrate string, framerate int) []string {
+	if codec == "copy" && !p.profile.LowLatency && width <= 0 && height <= 0 && bitrate == "" && framerate <= 0 {
+		return []string{"-c:v", "copy"}
+	}
+	if codec == "copy" {
+		codec = "libx264"
+	}
+	args := []string{"-c:v", codec}
+	if width > 0 || height > 0 {
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:%d", dimension(width), dimension(height)))
+	}
+	if bitrate != "" {
+		args = append(args, "-b:v", bitrate, "-maxrate", bitrate, "-bufsize", bitrate)
+	}
+	if framerate > 0 {
+		args = append(args, "-r", strconv.Itoa(framerate))
+	}
+	// Segments can only be cut at keyframes, so force one at every segment boundary
+	boundary := p.profile.SegmentLength
+	if p.profile.LowLatency {
+		boundary = p.profile.PartLength
+	}
+	return append(args, "-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%s)", seconds(boundary.Seconds())))
+}
+
+// dimension returns the ffmpeg scale value of a size, keeping the aspect ratio for unset ones
+func dimension(size int) int {
+	if size <= 0 {
+		return -2
+	}
+	return size
+}
+
+// seconds formats a duration in seconds the way ffmpeg accepts it
+func seconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', -1, 64)
+}
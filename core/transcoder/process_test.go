@@ -0,0 +1,111 @@
+package transcoder
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func resolve(t *testing.T, profile config.Profile) config.Profile {
+	spec := &config.Specification{}
+	spec.Profiles = map[string]config.Profile{"test": profile}
+	resolved, err := spec.Profile("test")
+	assert.Nil(t, err)
+	return resolved
+}
+
+func TestCopyArgs(t *testing.T) {
+	args := strings.Join(NewProcess(resolve(t, config.Profile{}), Recording{}).Args("/videos/id", "rtsp://host/1"), " ")
+	assert.Contains(t, args, "-i rtsp://host/1")
+	assert.Contains(t, args, "-c:v copy")
+	assert.Contains(t, args, "-an")
+	assert.Contains(t, args, "-hls_flags delete_segments+append_list")
+	assert.Contains(t, args, "-hls_time 1 -hls_list_size 3")
+	assert.True(t, strings.HasSuffix(args, "-hls_segment_filename /videos/id/%d.ts /videos/id/index.m3u8"))
+	assert.NotContains(t, args, "-vf")
+}
+
+func TestTranscodeArgs(t *testing.T) {
+	audio := true
+	keepFiles := true
+	args := strings.Join(NewProcess(resolve(t, config.Profile{
+		Width:         640,
+		Bitrate:       "800k",
+		Framerate:     15,
+		Audio:         &audio,
+		KeepFiles:     &keepFiles,
+		SegmentLength: 2 * time.Second,
+		ListSize:      5,
+	}), Recording{}).Args("/videos/id", "rtsp://host/1"), " ")
+	assert.Contains(t, args, "-c:v libx264")
+	assert.Contains(t, args, "-vf scale=640:-2")
+	assert.Contains(t, args, "-b:v 800k")
+	assert.Contains(t, args, "-r 15")
+	assert.Contains(t, args, "-force_key_frames expr:gte(t,n_forced*2)")
+	assert.Contains(t, args, "-c:a aac")
+	assert.Contains(t, args, "-hls_flags append_list")
+	assert.Contains(t, args, "-hls_time 2 -hls_list_size 5")
+}
+
+func TestRenditionArgs(t *testing.T) {
+	args := strings.Join(NewProcess(resolve(t, config.Profile{
+		Renditions: []config.Rendition{
+			{Name: "360p", Width: 640, Height: 360, Bitrate: "800k"},
+		},
+	}), Recording{}).Args("/videos/id", "rtsp://host/1"), " ")
+	assert.Contains(t, args, "-c:v copy -an -f hls")
+	assert.Contains(t, args, "/videos/id/index.m3u8 -c:v libx264 -vf scale=640:360 -b:v 800k")
+	assert.True(t, strings.HasSuffix(args, "-hls_segment_filename /videos/id/360p/%d.ts /videos/id/360p/index.m3u8"))
+}
+
+func TestDashArgs(t *testing.T) {
+	dash := true
+	args := strings.Join(NewProcess(resolve(t, config.Profile{Dash: &dash}), Recording{}).Args("/videos/id", "rtsp://host/1"), " ")
+	assert.Contains(t, args, "/videos/id/index.m3u8 -c:v copy -an -f dash")
+	assert.Contains(t, args, "-window_size 3")
+	assert.True(t, strings.HasSuffix(args, "/videos/id/manifest.mpd"))
+
+	args = strings.Join(NewProcess(resolve(t, config.Profile{}), Recording{}).Args("/videos/id", "rtsp://host/1"), " ")
+	assert.NotContains(t, args, "-f dash")
+}
+
+func TestLowLatencyArgs(t *testing.T) {
+	args := strings.Join(NewProcess(resolve(t, config.Profile{
+		LowLatency:    true,
+		SegmentLength: 2 * time.Second,
+		PartLength:    500 * time.Millisecond,
+	}), Recording{}).Args("/videos/id", "rtsp://host/1"), " ")
+	// copied video is re-encoded to force keyframes at part boundaries
+	assert.Contains(t, args, "-c:v libx264 -force_key_frames expr:gte(t,n_forced*0.5)")
+	assert.Contains(t, args, "-hls_segment_type fmp4")
+	assert.Contains(t, args, "-hls_flags delete_segments+append_list+temp_file")
+	assert.Contains(t, args, "-hls_time 0.5 -hls_list_size 16")
+	assert.True(t, strings.HasSuffix(args, "-hls_segment_filename /videos/id/part%d.m4s /videos/id/index.m3u8"))
+}
+
+func TestForcesKeyframes(t *testing.T) {
+	assert.False(t, ForcesKeyframes(resolve(t, config.Profile{})))
+	assert.True(t, ForcesKeyframes(resolve(t, config.Profile{LowLatency: true})))
+	assert.True(t, ForcesKeyframes(resolve(t, config.Profile{Bitrate: "800k"})))
+}
+
+func TestRecordArgs(t *testing.T) {
+	args := strings.Join(NewProcess(resolve(t, config.Profile{}), Recording{
+		Dir:           "/recordings/id",
+		SegmentLength: 10 * time.Minute,
+	}).Args("/videos/id", "rtsp://host/1"), " ")
+	assert.Contains(t, args, "/videos/id/index.m3u8 -c:v copy -an -f segment -segment_time 600 -segment_format mp4")
+	assert.True(t, strings.HasSuffix(args, "-strftime 1 /recordings/id/%Y/%m/%d/%H-%M-%S.mp4"))
+}
+
+func TestDVRArgs(t *testing.T) {
+	args := strings.Join(NewProcess(resolve(t, config.Profile{
+		SegmentLength: 2 * time.Second,
+		DVRWindow:     time.Minute,
+	}), Recording{}).Args("/videos/id", "rtsp://host/1"), " ")
+	assert.Contains(t, args, "-hls_flags delete_segments+append_list+program_date_time")
+	assert.Contains(t, args, "-hls_time 2 -hls_list_size 33")
+}
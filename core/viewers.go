@@ -0,0 +1,45 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// viewerKey identifies the client of the request for viewer accounting.
+// Sessions given by the player win over tokens, which win over the address of the client.
+// Tokens are read from the carriers of the static endpoint, the same way as for authentication.
+func (c *Controller) viewerKey(r *http.Request) string {
+	if session := r.URL.Query().Get("session"); session != "" {
+		return "session:" + session
+	}
+	if token, _ := c.tokenCarrier(r, "static"); token != "" {
+		// the same token counts once whichever carrier it is sent in
+		token = strings.TrimPrefix(token, "Bearer ")
+		// tokens are not kept in memory as they are
+		sum := sha256.Sum256([]byte(token))
+		return "token:" + hex.EncodeToString(sum[:8])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// idleTimeout returns the time without viewers after the stream is stopped by the cleanup.
+// The option of the stream wins over the one of its profile.
+func (c *Controller) idleTimeout(id string) time.Duration {
+	opts := c.registry.Options(id)
+	if opts.IdleTimeout > 0 {
+		return time.Duration(opts.IdleTimeout)
+	}
+	profile, err := c.spec.Profile(opts.Profile)
+	if err != nil {
+		return c.spec.IdleTimeout
+	}
+	return profile.IdleTimeout
+}
@@ -0,0 +1,130 @@
+package viewers
+
+import (
+	"sync"
+	"time"
+)
+
+// ITracker describes the user panel of the viewer accounting
+type ITracker interface {
+	Hit(id string, viewer string)
+	Touch(id string)
+	Count(id string) int
+	IdleSince(id string) (time.Time, bool)
+	LastSeen(id string) time.Time
+	Remove(id string)
+}
+
+// entry holds the viewers of a single stream
+type entry struct {
+	viewers map[string]time.Time
+	last    time.Time
+	idle    time.Time // Time the last viewer left or the stream was touched without viewers
+}
+
+// Tracker counts the distinct clients requesting the files of each stream
+type Tracker struct {
+	mu      *sync.Mutex
+	window  time.Duration
+	streams map[string]*entry
+}
+
+// Type check
+var _ ITracker = (*Tracker)(nil)
+
+// NewTracker creates a new Tracker. Clients are counted as viewers for window after their last request.
+func NewTracker(window time.Duration) *Tracker {
+	return &Tracker{
+		mu:      &sync.Mutex{},
+		window:  window,
+		streams: map[string]*entry{},
+	}
+}
+
+// prune forgets the viewers of the entry whose window is over. Callers have to hold mu.
+func (t *Tracker) prune(e *entry) {
+	for viewer, seen := range e.viewers {
+		if time.Since(seen) > t.window {
+			delete(e.viewers, viewer)
+			if left := seen.Add(t.window); left.After(e.idle) {
+				e.idle = left
+			}
+		}
+	}
+}
+
+// get returns the entry of the stream, creating it if needed. Callers have to hold mu.
+func (t *Tracker) get(id string) *entry {
+	e, ok := t.streams[id]
+	if !ok {
+		e = &entry{viewers: map[string]time.Time{}}
+		t.streams[id] = e
+	}
+	return e
+}
+
+// Hit registers a request of the viewer for the stream
+func (t *Tracker) Hit(id string, viewer string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	e := t.get(id)
+	e.viewers[viewer] = now
+	e.last = now
+}
+
+// Touch marks the stream as watched without a viewer, used when it is started,
+// so it is not idle before its first viewer arrives
+func (t *Tracker) Touch(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	e := t.get(id)
+	e.last = now
+	e.idle = now
+}
+
+// Count returns the number of viewers who requested the stream within the window
+func (t *Tracker) Count(id string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.streams[id]
+	if !ok {
+		return 0
+	}
+	t.prune(e)
+	return len(e.viewers)
+}
+
+// IdleSince returns the time since the stream has no viewers, false while it has any.
+// Streams never requested or touched are idle since the zero time.
+func (t *Tracker) IdleSince(id string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.streams[id]
+	if !ok {
+		return time.Time{}, true
+	}
+	t.prune(e)
+	if len(e.viewers) > 0 {
+		return time.Time{}, false
+	}
+	return e.idle, true
+}
+
+// LastSeen returns the time of the last request or touch of the stream, zero time if there was none
+func (t *Tracker) LastSeen(id string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.streams[id]; ok {
+		return e.last
+	}
+	return time.Time{}
+}
+
+// Remove forgets the viewers of the stream
+func (t *Tracker) Remove(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.streams, id)
+}
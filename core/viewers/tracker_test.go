@@ -0,0 +1,47 @@
+package viewers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(50 * time.Millisecond)
+	assert.Equal(t, 0, tracker.Count("id"))
+	assert.True(t, tracker.LastSeen("id").IsZero())
+
+	since, idle := tracker.IdleSince("id")
+	assert.True(t, idle)
+	assert.True(t, since.IsZero())
+
+	tracker.Touch("id")
+	assert.Equal(t, 0, tracker.Count("id"))
+	assert.False(t, tracker.LastSeen("id").IsZero())
+	since, idle = tracker.IdleSince("id")
+	assert.True(t, idle)
+	assert.Equal(t, tracker.LastSeen("id"), since)
+
+	tracker.Hit("id", "ip:1.1.1.1")
+	tracker.Hit("id", "ip:1.1.1.1")
+	tracker.Hit("id", "ip:2.2.2.2")
+	tracker.Hit("other", "ip:1.1.1.1")
+	assert.Equal(t, 2, tracker.Count("id"))
+	assert.Equal(t, 1, tracker.Count("other"))
+	_, idle = tracker.IdleSince("id")
+	assert.False(t, idle)
+
+	time.Sleep(60 * time.Millisecond)
+	tracker.Hit("id", "ip:2.2.2.2")
+	assert.Equal(t, 1, tracker.Count("id"))
+	// the stream is idle since the window of its last viewer is over
+	last := tracker.LastSeen("other")
+	since, idle = tracker.IdleSince("other")
+	assert.True(t, idle)
+	assert.Equal(t, last.Add(50*time.Millisecond), since)
+
+	tracker.Remove("id")
+	assert.Equal(t, 0, tracker.Count("id"))
+	assert.True(t, tracker.LastSeen("id").IsZero())
+}
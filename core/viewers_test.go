@@ -0,0 +1,73 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/viewers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewerKey(t *testing.T) {
+	c := newTestController()
+	c.endpoints.Store(config.Endpoints{Static: config.EndpointSetting{
+		Enabled:  true,
+		Carriers: []string{config.CarrierHeader, config.CarrierCookie, config.CarrierQuery},
+	}})
+	r := httptest.NewRequest(http.MethodGet, "/stream/id/index.m3u8", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	assert.Equal(t, "ip:10.0.0.1", c.viewerKey(r))
+
+	r.Header.Set("Authorization", "Bearer token")
+	key := c.viewerKey(r)
+	assert.Contains(t, key, "token:")
+	assert.NotContains(t, key, "Bearer")
+
+	// the token is the same viewer in every carrier
+	r = httptest.NewRequest(http.MethodGet, "/stream/id/index.m3u8", nil)
+	r.AddCookie(&http.Cookie{Name: TokenCookie, Value: "token"})
+	assert.Equal(t, key, c.viewerKey(r))
+	r = httptest.NewRequest(http.MethodGet, "/stream/id/index.m3u8?"+TokenParam+"=token", nil)
+	assert.Equal(t, key, c.viewerKey(r))
+
+	r = httptest.NewRequest(http.MethodGet, "/stream/id/index.m3u8?session=abc", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	assert.Equal(t, "session:abc", c.viewerKey(r))
+}
+
+func TestIdleTimeout(t *testing.T) {
+	c := newTestController()
+	c.spec.IdleTimeout = time.Minute
+	c.spec.Profiles = map[string]config.Profile{"short": {IdleTimeout: 10 * time.Second}}
+	c.registry.SetOptions("default", config.StreamOptions{})
+	c.registry.SetOptions("profile", config.StreamOptions{Profile: "short"})
+	c.registry.SetOptions("stream", config.StreamOptions{Profile: "short", IdleTimeout: config.Duration(5 * time.Second)})
+	assert.Equal(t, time.Minute, c.idleTimeout("default"))
+	assert.Equal(t, 10*time.Second, c.idleTimeout("profile"))
+	assert.Equal(t, 5*time.Second, c.idleTimeout("stream"))
+}
+
+func TestViewerCleanup(t *testing.T) {
+	c := newTestController()
+	c.spec.IdleTimeout = time.Minute
+	c.viewers = viewers.NewTracker(5 * time.Millisecond)
+	watched := newIdleStream("watched", "rtsp://host/1")
+	idle := newIdleStream("idle", "rtsp://host/2")
+	active := newIdleStream("active", "rtsp://host/3")
+	c.registry.Add(watched, "").Add(idle, "").Add(active, "")
+	c.registry.SetOptions("idle", config.StreamOptions{IdleTimeout: config.Duration(time.Millisecond)})
+	c.registry.SetOptions("active", config.StreamOptions{IdleTimeout: config.Duration(time.Millisecond)})
+	c.viewers.Hit("watched", "ip:10.0.0.1")
+	c.viewers.Hit("idle", "ip:10.0.0.1")
+	time.Sleep(10 * time.Millisecond)
+	// viewers within the window keep the stream regardless of its idle timeout
+	c.viewers.Hit("active", "ip:10.0.0.1")
+
+	c.stopInactiveStreams()
+	assert.True(t, watched.Running)
+	assert.False(t, idle.Running)
+	assert.True(t, active.Running)
+}
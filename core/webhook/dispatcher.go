@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/events"
+	"github.com/sirupsen/logrus"
+)
+
+// SignatureHeader is the header carrying the HMAC-SHA256 signature of signed payloads
+const SignatureHeader = "X-RTSP-Stream-Signature"
+
+// EventHeader is the header carrying the type of the delivered event
+const EventHeader = "X-RTSP-Stream-Event"
+
+// workers is the number of deliveries sent at the same time
+const workers = 4
+
+// delivery describes an event waiting to be sent to a webhook
+type delivery struct {
+	hook  config.Webhook
+	event events.Event
+}
+
+// Dispatcher sends the events of the bus to the configured webhooks
+type Dispatcher struct {
+	hooks   []config.Webhook
+	queue   chan delivery
+	client  *http.Client
+	retries int
+	backoff time.Duration
+}
+
+// NewDispatcher creates a new Dispatcher for the given webhooks and delivery settings
+func NewDispatcher(hooks []config.Webhook, settings config.WebhookDelivery) *Dispatcher {
+	size := settings.WebhookQueueSize
+	if size < 0 {
+		size = 0
+	}
+	return &Dispatcher{
+		hooks:   hooks,
+		queue:   make(chan delivery, size),
+		client:  &http.Client{Timeout: settings.WebhookTimeout},
+		retries: settings.WebhookRetries,
+		backoff: settings.WebhookBackoff,
+	}
+}
+
+// Run queues the deliveries of every event received from the channel until it is closed.
+// Deliveries are dropped when the queue is full, so unreachable webhooks cannot block the application.
+func (d *Dispatcher) Run(ch <-chan events.Event) {
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	for event := range ch {
+		for _, hook := range d.hooks {
+			if !subscribed(hook, event.Type) {
+				continue
+			}
+			select {
+			case d.queue <- delivery{hook, event}:
+			default:
+				logrus.Errorf("Dropping %s event for %s, queue is full | Webhook", event.Type, hook.URL)
+			}
+		}
+	}
+	close(d.queue)
+}
+
+// work sends queued deliveries, retrying failed ones with exponential backoff
+func (d *Dispatcher) work() {
+	for item := range d.queue {
+		wait := d.backoff
+		for attempt := 0; ; attempt++ {
+			err := d.send(item.hook, item.event)
+			if err == nil {
+				break
+			}
+			if attempt >= d.retries {
+				logrus.Errorf("Could not deliver %s event to %s: %s | Webhook", item.event.Type, item.hook.URL, err)
+				break
+			}
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+}
+
+// send posts the event to the webhook, responses other than 2xx are failures
+func (d *Dispatcher) send(hook config.Webhook, event events.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Type)
+	if hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the signature of the payload in the format of SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// subscribed shows if the webhook wants events of the given type
+func subscribed(hook config.Webhook, eventType string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, t := range hook.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Roverr/rtsp-stream/core/config"
+	"github.com/Roverr/rtsp-stream/core/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcher(t *testing.T) {
+	mu := &sync.Mutex{}
+	calls := 0
+	received := make(chan events.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		fail := calls == 1
+		mu.Unlock()
+		// first delivery fails to test retries
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, Sign("secret", body), r.Header.Get(SignatureHeader))
+		assert.Equal(t, events.Timeout, r.Header.Get(EventHeader))
+		event := events.Event{}
+		assert.Nil(t, json.Unmarshal(body, &event))
+		received <- event
+	}))
+	defer server.Close()
+
+	d := NewDispatcher([]config.Webhook{
+		{URL: server.URL, Secret: "secret", Events: []string{events.Timeout}},
+	}, config.WebhookDelivery{WebhookQueueSize: 10, WebhookRetries: 2, WebhookBackoff: time.Millisecond, WebhookTimeout: time.Second})
+	ch := make(chan events.Event)
+	go d.Run(ch)
+	ch <- events.Event{Seq: 1, Type: events.Started, URI: "rtsp://host/1"}
+	ch <- events.Event{Seq: 2, Type: events.Timeout, URI: "rtsp://host/1"}
+	close(ch)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, uint64(2), event.Seq)
+	case <-time.After(2 * time.Second):
+		t.Fatal("event is not delivered")
+	}
+	mu.Lock()
+	assert.Equal(t, 2, calls)
+	mu.Unlock()
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", Sign("key", []byte("The quick brown fox jumps over the lazy dog")))
+}
+
+func TestSubscribed(t *testing.T) {
+	assert.True(t, subscribed(config.Webhook{}, events.Started))
+	assert.True(t, subscribed(config.Webhook{Events: []string{events.Failed}}, events.Failed))
+	assert.False(t, subscribed(config.Webhook{Events: []string{events.Failed}}, events.Started))
+}
@@ -11,12 +11,15 @@ import (
 	"github.com/rs/cors"
 
 	"github.com/Roverr/rtsp-stream/core"
-	"github.com/Roverr/rtsp-stream/core/config"
+	cfg "github.com/Roverr/rtsp-stream/core/config"
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
-	config := config.InitConfig()
+	if len(os.Args) > 1 && os.Args[1] == "check-config" {
+		checkConfig(os.Args[2:])
+	}
+	config := cfg.InitConfig(os.Args[1:])
 	core.SetupLogger(config)
 	fileServer := http.FileServer(http.Dir(config.StoreDir))
 	router := httprouter.New()
@@ -24,22 +27,33 @@ func main() {
 	router.GET("/", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		w.WriteHeader(http.StatusOK)
 	})
-	if config.EndpointYML.Endpoints.List.Enabled {
-		router.GET("/list", controllers.ListStreamHandler)
-		logrus.Infoln("list endpoint enabled | MainProcess")
-	}
-	if config.EndpointYML.Endpoints.Start.Enabled {
-		router.POST("/start", controllers.StartStreamHandler)
-		logrus.Infoln("start endpoint enabled | MainProcess")
-	}
-	if config.EndpointYML.Endpoints.Static.Enabled {
-		router.GET("/stream/*filepath", controllers.StaticFileHandler)
-		logrus.Infoln("static endpoint enabled | MainProcess")
-	}
-	if config.EndpointYML.Endpoints.Stop.Enabled {
-		router.POST("/stop", controllers.StopStreamHandler)
-		logrus.Infoln("stop endpoint enabled | MainProcess")
-	}
+	router.GET("/list", controllers.Instrument("/list", controllers.Enabled("list", controllers.ListStreamHandler)))
+	router.POST("/start", controllers.Instrument("/start", controllers.Enabled("start", controllers.StartStreamHandler)))
+	router.GET("/stream/*filepath", controllers.Instrument("/stream/*filepath", controllers.Enabled("static", controllers.StaticFileHandler)))
+	router.POST("/stop", controllers.Instrument("/stop", controllers.Enabled("stop", controllers.StopStreamHandler)))
+	router.GET("/recordings", controllers.Instrument("/recordings", controllers.Enabled("recordings", controllers.ListRecordingsHandler)))
+	router.GET("/recordings/*filepath", controllers.Instrument("/recordings/*filepath", controllers.Enabled("recordings", controllers.RecordingFileHandler)))
+	router.GET("/snapshot/:file", controllers.Instrument("/snapshot/:file", controllers.Enabled("snapshot", controllers.SnapshotHandler)))
+	router.GET("/listen/:alias", controllers.Instrument("/listen/:alias", controllers.Enabled("listen", controllers.GetListenHandler)))
+	router.PUT("/listen/:alias", controllers.Instrument("/listen/:alias", controllers.Enabled("listen", controllers.PutListenHandler)))
+	router.DELETE("/listen/:alias", controllers.Instrument("/listen/:alias", controllers.Enabled("listen", controllers.DeleteListenHandler)))
+	router.GET("/sign/:id", controllers.Instrument("/sign/:id", controllers.Enabled("sign", controllers.SignHandler)))
+	router.POST("/login", controllers.Instrument("/login", controllers.Enabled("login", controllers.LoginHandler)))
+	router.DELETE("/login", controllers.Instrument("/login", controllers.Enabled("login", controllers.LogoutHandler)))
+	// long lived responses are not instrumented, the latency would only measure how long clients listen
+	router.GET("/events", controllers.Enabled("events", controllers.EventsHandler))
+	router.GET("/metrics", controllers.Enabled("metrics", controllers.MetricsHandler))
+	logEndpoints(config.Endpoints)
+
+	go cfg.Watch(config.ConfigFile, config.ConfigWatchTime, func() {
+		yml, err := cfg.LoadEndpointYML(config.ConfigFile)
+		if err != nil {
+			logrus.Errorf("Keeping previous configuration, %s is invalid: %s | Reload", config.ConfigFile, err)
+			return
+		}
+		controllers.Reload(yml)
+		logEndpoints(yml.Endpoints)
+	})
 
 	done := controllers.ExitPreHook()
 	handler := cors.AllowAll().Handler(router)
@@ -64,3 +78,25 @@ func main() {
 	}
 	os.Exit(0)
 }
+
+// logEndpoints logs the endpoints currently enabled
+func logEndpoints(endpoints cfg.Endpoints) {
+	for _, name := range cfg.EndpointNames {
+		if setting, _ := endpoints.Setting(name); setting.Enabled {
+			logrus.Infof("%s endpoint enabled | MainProcess", name)
+		}
+	}
+}
+
+// checkConfig prints every problem of the configuration and exits, with non-zero status if there is any
+func checkConfig(args []string) {
+	problems := cfg.Check(args)
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("Configuration is valid")
+	os.Exit(0)
+}